@@ -0,0 +1,104 @@
+package mdsplit
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+
+	markdown "github.com/teekennedy/goldmark-markdown"
+)
+
+// alertMarker matches the first line of a GitHub alert, such as [!NOTE].
+var alertMarker = regexp.MustCompile(`^\[!(NOTE|TIP|IMPORTANT|WARNING|CAUTION)\]\s*$`)
+
+// quote is a blockquote rendered child by child, ready to be split.
+type quote struct {
+	alert    string  // Alert marker such as [!NOTE], or empty
+	children []block // Rendered child blocks without their quote prefix
+}
+
+// renderQuote renders the children of a blockquote one at a time. The
+// renderer drops the blank lines between the children of a blockquote, so
+// rendering them separately is the only way to keep them apart.
+func renderQuote(b block, renderer *markdown.Renderer, source []byte, lay layout) (quote, error) {
+	var q quote
+	for child := b.node.FirstChild(); child != nil; child = child.NextSibling() {
+		var content bytes.Buffer
+		if err := safeRender(renderer, &content, source, child, lay); err != nil {
+			return quote{}, err
+		}
+		lines := trimmedLines(bytes.TrimLeft(content.Bytes(), "\n"))
+		if child == b.node.FirstChild() && len(lines) > 0 && alertMarker.MatchString(lines[0]) {
+			q.alert = strings.TrimSpace(lines[0])
+			lines = lines[1:]
+			if len(lines) == 0 {
+				continue
+			}
+		}
+		text := strings.Join(lines, "\n") + "\n"
+		start, stop := sourceRange(child, source)
+		q.children = append(q.children, block{
+			node:    child,
+			content: []byte(text),
+			lines:   lay.lineCount(text, child),
+			start:   start,
+			stop:    stop,
+		})
+	}
+	return q, nil
+}
+
+// split splits the quote into parts of at most maxHeight visual lines,
+// breaking only between its child blocks. Every part is quoted again and
+// repeats the alert marker so the alert keeps its styling. The first part
+// shares its slide with lead lines of earlier content, and note lines are
+// left for a continuation note.
+func (q quote) split(maxHeight, lead, note int) []slidePart {
+	budget := maxHeight - noteSpace(note)
+	if q.alert != "" {
+		budget-- // Account for the repeated alert marker.
+	}
+
+	var parts []slidePart
+	for first := 0; first < len(q.children); {
+		room := budget
+		if first == 0 {
+			room -= lead
+		}
+		used, count := 0, 0
+		for _, child := range q.children[first:] {
+			height := child.lines
+			if count > 0 {
+				height++ // The quoted blank line between blocks.
+			}
+			if count > 0 && used+height > room {
+				break
+			}
+			used += height
+			count++
+		}
+
+		var slideContent bytes.Buffer
+		if q.alert != "" {
+			slideContent.WriteString("> " + q.alert + "\n")
+		}
+		part := slidePart{start: -1, stop: -1}
+		for i, child := range q.children[first : first+count] {
+			if i > 0 {
+				slideContent.WriteString(">\n")
+			}
+			for _, line := range trimmedLines(child.content) {
+				if line == "" {
+					slideContent.WriteString(">\n")
+					continue
+				}
+				slideContent.WriteString("> " + line + "\n")
+			}
+			part.start, part.stop = widenRange(part.start, part.stop, child.start, child.stop)
+		}
+		part.content = slideContent.Bytes()
+		parts = append(parts, part)
+		first += count
+	}
+	return parts
+}
@@ -0,0 +1,133 @@
+package mdsplit
+
+import "sort"
+
+// breakLimits holds the fewest lines of a split block allowed on either side
+// of a slide break.
+type breakLimits struct {
+	before, after int
+}
+
+// chunker packs the units of an oversized block, such as table rows or code
+// lines, into parts that each fit on a slide.
+type chunker struct {
+	count  int                       // Number of units
+	height func(first, last int) int // Visual height of units[first:last]
+	room   func(part int) int        // Height available to units on a part
+}
+
+// prefixHeights returns a height function for units of the given heights.
+func prefixHeights(heights []int) func(first, last int) int {
+	sums := make([]int, len(heights)+1)
+	for i, h := range heights {
+		sums[i+1] = sums[i] + h
+	}
+	return func(first, last int) int {
+		return sums[last] - sums[first]
+	}
+}
+
+// fit returns the end of the longest run of units from first that fits on
+// part. At least one unit is always taken.
+func (c chunker) fit(first, part int) int {
+	room := c.room(part)
+	last := first + sort.Search(c.count-first, func(i int) bool {
+		return c.height(first, first+i+1) > room
+	})
+	return max(last, first+1)
+}
+
+// greedy fills each part with as many units as fit and returns the unit
+// index each part starts at, followed by count.
+func (c chunker) greedy() []int {
+	bounds := []int{0}
+	for first := 0; first < c.count; {
+		first = c.fit(first, len(bounds)-1)
+		bounds = append(bounds, first)
+	}
+	return bounds
+}
+
+// even packs units into as few parts as greedy does, but shares them out so
+// the parts are of roughly even height.
+func (c chunker) even() []int {
+	greedy := c.greedy()
+	if len(greedy) < 3 {
+		return greedy // A single part has nothing to share.
+	}
+	parts := len(greedy) - 1
+	bounds := []int{0}
+	for first := 0; first < c.count; {
+		left := max(parts-(len(bounds)-1), 1)
+		target := (c.height(first, c.count) + left - 1) / left
+		last := c.fit(first, len(bounds)-1)
+		// Take the fewest units reaching the target height.
+		k := first + 1 + sort.Search(last-first-1, func(i int) bool {
+			return c.height(first, first+i+1) >= target
+		})
+		bounds = append(bounds, k)
+		first = k
+	}
+	return bounds
+}
+
+// pack returns the unit index each part starts at, followed by count, for
+// the given strategy, with breaks moved to respect limits.
+func (c chunker) pack(strategy Strategy, limits breakLimits) []int {
+	bounds := c.greedy()
+	if strategy == StrategyBalanced || strategy == StrategyOptimal {
+		bounds = c.even()
+	}
+	return c.balance(bounds, limits)
+}
+
+// balance reshapes the parts when a break leaves fewer than limits.before
+// lines on the part before it or fewer than limits.after lines on the part
+// after it. The units are first shared out evenly over as few parts as fit,
+// and if a break still breaks a limit, the lines of the two parts around it
+// are shared as evenly as fits. A break is only moved where both parts then
+// meet the limits.
+func (c chunker) balance(bounds []int, limits breakLimits) []int {
+	if c.meets(bounds, limits) {
+		return bounds
+	}
+	if even := c.even(); len(even) <= len(bounds) && c.meets(even, limits) {
+		return even
+	}
+	for i := len(bounds) - 2; i > 0; i-- {
+		first, last := bounds[i-1], bounds[i+1]
+		if c.height(first, bounds[i]) >= limits.before && c.height(bounds[i], last) >= limits.after {
+			continue
+		}
+		best, bestDiff := bounds[i], -1
+		for k := first + 1; k < last; k++ {
+			before, after := c.height(first, k), c.height(k, last)
+			if before < limits.before || after < limits.after || before > c.room(i-1) || after > c.room(i) {
+				continue
+			}
+			if diff := abs(before - after); bestDiff < 0 || diff < bestDiff {
+				best, bestDiff = k, diff
+			}
+		}
+		bounds[i] = best
+	}
+	return bounds
+}
+
+// meets reports whether every break in bounds leaves at least limits.before
+// lines before it and limits.after lines after it on the parts around it.
+func (c chunker) meets(bounds []int, limits breakLimits) bool {
+	for i := 1; i < len(bounds)-1; i++ {
+		if c.height(bounds[i-1], bounds[i]) < limits.before || c.height(bounds[i], bounds[i+1]) < limits.after {
+			return false
+		}
+	}
+	return true
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,40 @@
+package mdsplit
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestChunkerBalance(t *testing.T) {
+	testCases := []struct {
+		name     string
+		heights  []int
+		room     int
+		bounds   []int // Breaks to balance (default: greedy)
+		limits   breakLimits
+		expected []int
+	}{
+		{name: "no limits", heights: []int{1, 1, 1, 1, 1, 1, 1}, room: 3, expected: []int{0, 3, 6, 7}},
+		{name: "short last part", heights: []int{1, 1, 1, 1, 1, 1, 1}, room: 3, limits: breakLimits{after: 2}, expected: []int{0, 3, 5, 7}},
+		{name: "lines left before", heights: []int{1, 1, 1, 1}, room: 3, limits: breakLimits{before: 3, after: 2}, expected: []int{0, 3, 4}},
+		{name: "uneven parts", heights: []int{1, 1, 1, 1, 1, 1, 1, 1, 1, 1}, room: 4, limits: breakLimits{after: 3}, expected: []int{0, 4, 7, 10}},
+		{name: "short part before a break", heights: []int{1, 1, 1, 1, 1, 1}, room: 4, bounds: []int{0, 1, 4, 6}, limits: breakLimits{before: 2}, expected: []int{0, 3, 6}},
+		{name: "tall lines", heights: []int{2, 1, 1, 2, 1}, room: 4, limits: breakLimits{after: 4}, expected: []int{0, 2, 5}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := chunker{
+				count:  len(tc.heights),
+				height: prefixHeights(tc.heights),
+				room:   func(int) int { return tc.room },
+			}
+			bounds := tc.bounds
+			if bounds == nil {
+				bounds = c.greedy()
+			}
+			if got := c.balance(bounds, tc.limits); !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("bounds = %v, expected %v", got, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package mdsplit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// CleanMode selects what a DirWriter does with files an earlier run left in
+// its directory.
+type CleanMode string
+
+const (
+	// CleanNone leaves them, overwriting only the files written again.
+	CleanNone CleanMode = ""
+	// CleanSwap writes into a temporary directory next to the output and
+	// swaps it in for the old one on Close, dropping everything else the old
+	// directory held. A failed run leaves the old directory as it was. The
+	// swap is two renames rather than one atomic step, so the directory is
+	// briefly missing, and it cannot be the working directory or hold it.
+	CleanSwap CleanMode = "swap"
+	// CleanManifest removes, on Close, the slides listed in the manifest.json
+	// of the earlier run that this run did not write again. Files mdsplit did
+	// not generate are left alone.
+	CleanManifest CleanMode = "manifest"
+)
+
+// ParseCleanMode parses a clean mode name: "" or "none", "swap" or
+// "manifest".
+func ParseCleanMode(s string) (CleanMode, error) {
+	switch s {
+	case "", "none":
+		return CleanNone, nil
+	case "swap":
+		return CleanSwap, nil
+	case "manifest":
+		return CleanManifest, nil
+	}
+	return "", fmt.Errorf("unknown clean mode %q", s)
+}
+
+// NewCleaningDirWriter returns a writer for dir that removes the stale files
+// of an earlier run as mode says. On a dry run nothing is written or removed,
+// and Removed reports what would have been.
+func NewCleaningDirWriter(dir string, mode CleanMode, dryRun bool) (*DirWriter, error) {
+	w := &DirWriter{Dir: dir, clean: mode, dryRun: dryRun, written: map[string]bool{}}
+	switch mode {
+	case CleanNone:
+	case CleanSwap:
+		if err := checkSwapDir(dir); err != nil {
+			return nil, err
+		}
+		if !dryRun {
+			parent := filepath.Dir(filepath.Clean(dir))
+			if err := os.MkdirAll(parent, 0755); err != nil {
+				return nil, err
+			}
+			temp, err := os.MkdirTemp(parent, "."+filepath.Base(dir)+"-")
+			if err != nil {
+				return nil, err
+			}
+			w.temp = temp
+		}
+	case CleanManifest:
+		owned, err := manifestFiles(dir)
+		if err != nil {
+			return nil, err
+		}
+		w.owned = owned
+	default:
+		return nil, fmt.Errorf("unknown clean mode %q", mode)
+	}
+	if !dryRun && w.temp == "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+// Removed returns the slash-separated names of the stale files Close removed,
+// or would have removed on a dry run.
+func (w *DirWriter) Removed() []string {
+	return w.removed
+}
+
+// Discard removes the temporary directory of a CleanSwap writer that was not
+// closed, such as after a failed run, leaving the old directory in place.
+func (w *DirWriter) Discard() error {
+	if w.temp == "" {
+		return nil
+	}
+	err := os.RemoveAll(w.temp)
+	w.temp = ""
+	return err
+}
+
+// checkSwapDir refuses a directory that swapping cannot replace: the working
+// directory or one holding it, which would be renamed out from under the
+// process.
+func checkSwapDir(dir string) error {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(abs, wd)
+	if err != nil {
+		return err
+	}
+	if rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("clean mode %q cannot replace %s, which holds the working directory: write the slides to a directory of their own", CleanSwap, dir)
+	}
+	return nil
+}
+
+// swap replaces the directory with the one written, recording the old files
+// that were not written again. It is not atomic: the old directory is moved
+// aside before the new one takes its place.
+func (w *DirWriter) swap() error {
+	err := filepath.WalkDir(w.Dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(w.Dir, p)
+		if err != nil {
+			return err
+		}
+		if name := filepath.ToSlash(rel); !w.written[name] {
+			w.removed = append(w.removed, name)
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if w.dryRun {
+		return nil
+	}
+
+	// MkdirTemp makes the directory private.
+	if err := os.Chmod(w.temp, 0755); err != nil {
+		return err
+	}
+	old := w.temp + ".old"
+	if err := os.Rename(w.Dir, old); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err := os.Rename(w.temp, w.Dir); err != nil {
+		return err
+	}
+	w.temp = ""
+	return os.RemoveAll(old)
+}
+
+// prune removes the files of the earlier run that were not written again.
+func (w *DirWriter) prune() error {
+	for _, name := range w.owned {
+		if w.written[name] {
+			continue
+		}
+		w.removed = append(w.removed, name)
+		if w.dryRun {
+			continue
+		}
+		if err := os.Remove(filepath.Join(w.Dir, filepath.FromSlash(name))); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// manifestFiles returns the slides listed in the manifest in dir, or none if
+// there is no manifest. Names reaching outside dir are ignored.
+func manifestFiles(dir string) ([]string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ManifestName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", ManifestName, err)
+	}
+	var files []string
+	for _, slide := range m.Slides {
+		if name := path.Clean(slide.File); fs.ValidPath(name) && !slices.Contains(files, name) {
+			files = append(files, name)
+		}
+	}
+	return files, nil
+}
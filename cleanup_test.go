@@ -0,0 +1,99 @@
+package mdsplit
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCleaningDirWriter(t *testing.T) {
+	long := "# Page 1\n\nOne.\n\n# Page 2\n\nTwo.\n\n# Page 3\n\nThree.\n"
+	short := "# Page 1\n\nOne.\n"
+
+	testCases := []struct {
+		name    string
+		mode    CleanMode
+		dryRun  bool
+		removed []string
+		left    []string
+	}{
+		{name: "none", mode: CleanNone, left: []string{"manifest.json", "notes.txt", "slide-1.md", "slide-2.md", "slide-3.md"}},
+		{name: "swap", mode: CleanSwap, removed: []string{"notes.txt", "slide-2.md", "slide-3.md"}, left: []string{"manifest.json", "slide-1.md"}},
+		{name: "manifest", mode: CleanManifest, removed: []string{"slide-2.md", "slide-3.md"}, left: []string{"manifest.json", "notes.txt", "slide-1.md"}},
+		{name: "manifest dry run", mode: CleanManifest, dryRun: true, removed: []string{"slide-2.md", "slide-3.md"}, left: []string{"manifest.json", "notes.txt", "slide-1.md", "slide-2.md", "slide-3.md"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "out")
+			if err := Split([]byte(long), SplitOptions{OutDir: dir, MaxHeight: 4, Manifest: true}); err != nil {
+				t.Fatalf("first Split failed: %v", err)
+			}
+			if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("mine"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			w, err := NewCleaningDirWriter(dir, tc.mode, tc.dryRun)
+			if err != nil {
+				t.Fatalf("NewCleaningDirWriter failed: %v", err)
+			}
+			if err := Split([]byte(short), SplitOptions{Writer: w, MaxHeight: 4, Manifest: true}); err != nil {
+				t.Fatalf("second Split failed: %v", err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close failed: %v", err)
+			}
+			if !reflect.DeepEqual(w.Removed(), tc.removed) {
+				t.Errorf("Removed = %q, expected %q", w.Removed(), tc.removed)
+			}
+
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var left []string
+			for _, e := range entries {
+				left = append(left, e.Name())
+			}
+			if !reflect.DeepEqual(left, tc.left) {
+				t.Errorf("left %q, expected %q", left, tc.left)
+			}
+			if matches, _ := filepath.Glob(filepath.Join(filepath.Dir(dir), ".out-*")); len(matches) > 0 {
+				t.Errorf("temporary directories left behind: %q", matches)
+			}
+		})
+	}
+}
+
+func TestCleaningDirWriterDiscard(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "out")
+	if err := Split([]byte("# Old\n"), SplitOptions{OutDir: dir}); err != nil {
+		t.Fatalf("Split failed: %v", err)
+	}
+	w, err := NewCleaningDirWriter(dir, CleanSwap, false)
+	if err != nil {
+		t.Fatalf("NewCleaningDirWriter failed: %v", err)
+	}
+	if err := w.WriteFile("slide-1.md", []byte("# New\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Discard(); err != nil {
+		t.Fatalf("Discard failed: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "slide-1.md")); string(data) != "# Old\n\n" {
+		t.Errorf("slide-1.md = %q after a discarded run", data)
+	}
+}
+
+func TestCleaningDirWriterSwapWorkingDir(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	for _, out := range []string{".", dir, filepath.Dir(dir)} {
+		if _, err := NewCleaningDirWriter(out, CleanSwap, false); err == nil {
+			t.Errorf("NewCleaningDirWriter(%q) swapped out the working directory", out)
+		}
+	}
+	if _, err := NewCleaningDirWriter("slides", CleanSwap, true); err != nil {
+		t.Errorf("NewCleaningDirWriter failed for a directory of its own: %v", err)
+	}
+}
@@ -1,35 +1,150 @@
 package mdsplit
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"time"
 )
 
 // Run is a subcommand `mdsplit`
 //
 // Flags:
-//   in:           --in            (default: "")           Markdown input file, or stdin when empty
-//   out:          --out           (default: ".")          Output directory for the split files
-//   maxHeight:    --max-height    (default: 0)            Maximum height of a slide in lines. Overridden by template selection.
-//   maxWidth:     --max-width     (default: 0)            Maximum width of a slide in pixels. Overridden by template selection.
-//   theme:        --theme         (default: "light")      light or dark
-//   templateSize: --template-size (default: "")           Predefined template size.
-//   fontSize:     --font-size     (default: 12)           Font size in points
-//   dpi:          --dpi           (default: 96)           DPI for rendering
+//   in:                --in                  (default: "")       Markdown input file or directory or glob pattern; stdin when no inputs are given
+//   out:               --out                 (default: ".")      Output directory for the split files
+//   maxHeight:         --max-height          (default: 0)        Maximum height of a slide in lines. Overridden by template selection.
+//   maxWidth:          --max-width           (default: 0)        Maximum width of a slide in pixels. Overridden by template selection.
+//   theme:             --theme               (default: "light")  light or dark
+//   templateSize:      --template-size       (default: "")       Predefined template size.
+//   fontSize:          --font-size           (default: 12)       Font size in points
+//   dpi:               --dpi                 (default: 96)       DPI for rendering
+//   format:            --format              (default: "dir")    Output format: dir/zip/tar/stream
+//   fontFile:          --font-file           (default: "")       TrueType/OpenType font to measure text with
+//   monoFontFile:      --mono-font-file      (default: "")       Font to measure code with
+//   headingBreakLevel: --heading-break-level (default: 0)        Start a new slide at headings of this level or above
+//   keepWithNext:      --keep-with-next      (default: false)    Never leave a heading at the bottom of a slide
+//   splitBySection:    --split-by-section    (default: false)    One slide per section regardless of slide height
+//   breakOn:           --break-on            (default: "")       Markers forcing a slide break: hr and/or comment
+//   listNote:          --list-note           (default: false)    Add a continuation note to each part of a split list
+//   minBefore:         --min-lines-before    (default: 0)        Fewest lines of a split block left before a slide break
+//   minAfter:          --min-lines-after     (default: 0)        Fewest lines of a split block carried past a slide break
+//   strategy:          --strategy            (default: "greedy") How content is shared between slides: greedy/balanced/optimal
+//   keyColumn:         --table-key-column    (default: 0)        Column repeated on each part of a table split by width
+//   notes:             --notes               (default: "")       Further kinds of split block to note: code/paragraph/blockquote
+//   noteLang:          --note-lang           (default: "en")     Language of the continuation notes: en/de/ja
+//   noteTemplates:     --note-templates      (default: "")       JSON file of note templates by block kind
+//   breadcrumb:        --breadcrumb          (default: false)    Repeat the headings above a section atop each slide continuing it
+//   frontMatter:       --front-matter        (default: false)    Prepend YAML front matter describing each slide
+//   manifest:          --manifest            (default: false)    Write manifest.json describing the slides
+//   index:             --index               (default: false)    Write index.md linking to the slides
+//   nameTemplate:      --name-template       (default: "")       Go template naming the slide files
+//   stableNames:       --stable-names        (default: false)    Name slides by stable IDs from their section headings
+//   clean:             --clean               (default: "")       Remove stale slides of an earlier run: swap/manifest
+//   dryRun:            --dry-run             (default: false)    List the stale files that cleaning would remove and write nothing
+//   jobs:              --jobs                (default: 0)        Number of input files split at once or 0 for one per CPU
+//   join:              --join                (default: false)    Split all inputs as one deck numbered in a single sequence
+//   documentBreaks:    --document-breaks     (default: false)    With join start a new slide at each input file
+//   titleSlides:       --title-slides        (default: false)    With join open each input file with a slide of its title
+//   inputs:            ...                                       Further Markdown files or directories or glob patterns
 //
 // Valid template sizes are: card, horizontal-card, presentation, a4.
-func Run(in string, out string, maxHeight int, maxWidth int, theme string, templateSize string, fontSize int, dpi int) error {
-	// Read the input from the specified file or stdin.
-	var data []byte
-	var err error
-	if in == "" {
-		data, err = io.ReadAll(os.Stdin)
-	} else {
-		data, err = os.ReadFile(in)
+//
+// Break markers are given as a comma separated list: hr breaks at thematic
+// breaks (---) and comment at <!-- mdsplit:break --> comments.
+//
+// Continuation notes are Go text/template templates run with NoteData. The
+// notes file maps block kinds (table, columns, list, code, paragraph and
+// blockquote) to templates, and an empty template turns a note off. The
+// breadcrumb kind styles the heading trail, joined from .Headings.
+//
+// Slide file names are Go text/template templates run with NameData, such as
+// {{.Base}}-{{.Number}}-{{.Slug}}.md. The default names slides slide-1.md,
+// slide-2.md and so on. Stable names, such as usage-2.md for the second slide
+// under the Usage heading, stay the same when other sections change.
+//
+// Clean up stale slides with swap to write into a temporary directory swapped
+// in for out when done, dropping everything else out held, or with manifest
+// to remove only the slides listed in the manifest of the earlier run, which
+// turns on --manifest. The swap is two renames, not an atomic step, so out is
+// briefly missing, and out must not be the working directory, as it is by
+// default.
+//
+// Several inputs, a directory, searched recursively for .md and .markdown
+// files, or a glob pattern, where ** matches any number of directories, split
+// each file into a directory of its own below out, mirroring where it was
+// found: docs/guide/intro.md given as docs goes in out/guide/intro. The
+// manifest and index then cover every file.
+//
+// With --join the inputs are instead joined, in order, into one deck, and
+// relative links and images are rewritten to resolve from out. A title slide
+// shows the title in a file's front matter, or else its file name.
+//
+// With the zip, tar and stream formats, out names the file to write, or - for stdout.
+func Run(in string, out string, maxHeight int, maxWidth int, theme string, templateSize string, fontSize int, dpi int, format string, fontFile string, monoFontFile string, headingBreakLevel int, keepWithNext bool, splitBySection bool, breakOn string, listNote bool, minBefore int, minAfter int, strategy string, keyColumn int, notes string, noteLang string, noteTemplates string, breadcrumb bool, frontMatter bool, manifest bool, index bool, nameTemplate string, stableNames bool, clean string, dryRun bool, jobs int, join bool, documentBreaks bool, titleSlides bool, inputs ...string) error {
+	markers, err := ParseBreakMarkers(breakOn)
+	if err != nil {
+		return err
+	}
+	if err := checkStrategy(Strategy(strategy)); err != nil {
+		return err
 	}
+	nameTemplate, err = slideNameTemplate(nameTemplate, stableNames)
 	if err != nil {
-		return fmt.Errorf("error reading input: %v", err)
+		return err
+	}
+	cleanMode, err := ParseCleanMode(clean)
+	if err != nil {
+		return err
+	}
+	noteKinds, err := ParseNoteKinds(notes)
+	if err != nil {
+		return err
+	}
+	templates, err := readNoteTemplates(noteTemplates)
+	if err != nil {
+		return err
+	}
+
+	// Find the input files, falling back to stdin when none are given.
+	if in != "" {
+		inputs = append([]string{in}, inputs...)
+	}
+	var files []input
+	multi := false
+	if len(inputs) > 0 {
+		files, multi, err = expandInputs(inputs, out)
+		if err != nil {
+			return fmt.Errorf("error finding input: %v", err)
+		}
+	}
+
+	// Read a single input from the specified file or stdin, or every input
+	// when joining them.
+	var data []byte
+	var docs []Document
+	source := ""
+	if join && len(files) > 0 {
+		for _, f := range files {
+			d, err := os.ReadFile(f.path)
+			if err != nil {
+				return fmt.Errorf("error reading input: %v", err)
+			}
+			docs = append(docs, Document{Name: f.path, Data: d})
+		}
+	} else if !multi {
+		if len(files) == 0 {
+			data, err = io.ReadAll(os.Stdin)
+		} else {
+			source = files[0].path
+			data, err = os.ReadFile(source)
+		}
+		if err != nil {
+			return fmt.Errorf("error reading input: %v", err)
+		}
 	}
 
 	// Create the SplitOptions struct.
@@ -41,11 +156,254 @@ func Run(in string, out string, maxHeight int, maxWidth int, theme string, templ
 		TemplateSize: TemplateSize(templateSize),
 		FontSize:     fontSize,
 		DPI:          dpi,
+		FontFile:     fontFile,
+		MonoFontFile: monoFontFile,
+
+		HeadingBreakLevel:   headingBreakLevel,
+		KeepHeadingWithNext: keepWithNext,
+		SplitBySection:      splitBySection,
+		BreakOn:             markers,
+
+		ListContinuationNote: listNote,
+
+		MinLinesBeforeBreak: minBefore,
+		MinLinesAfterBreak:  minAfter,
+
+		Strategy:       Strategy(strategy),
+		TableKeyColumn: keyColumn,
+
+		ContinuationNotes: noteKinds,
+		NoteTemplates:     templates,
+		NoteLanguage:      noteLang,
+		HeadingBreadcrumb: breadcrumb,
+
+		SlideFrontMatter: frontMatter,
+		SourceName:       source,
+		Manifest:         manifest,
+		Index:            index,
+		NameTemplate:     nameTemplate,
+
+		DocumentBreaks: documentBreaks,
+		TitleSlides:    titleSlides,
+	}
+	if format != "" && format != "dir" {
+		// Links in joined documents resolve from where the output is unpacked.
+		opts.OutDir = filepath.Dir(out)
+	}
+	if cleanMode == CleanManifest {
+		// The next run finds the slides to remove in the manifest.
+		opts.Manifest = true
+	}
+
+	// Pick where the slides go.
+	w, closeOut, err := newFormatWriter(format, out, cleanMode, dryRun)
+	if err != nil {
+		return fmt.Errorf("error opening output: %v", err)
 	}
+	opts.Writer = w
 
-	// Split the Markdown file.
-	if err := Split(data, opts); err != nil {
+	// Split the Markdown files.
+	switch {
+	case docs != nil:
+		err = SplitDocuments(docs, opts)
+	case multi:
+		err = splitFiles(files, opts, jobs)
+	default:
+		err = Split(data, opts)
+	}
+	if err != nil {
+		closeOut()
 		return fmt.Errorf("error splitting Markdown: %v", err)
 	}
+	if err := w.Close(); err != nil {
+		closeOut()
+		return fmt.Errorf("error writing output: %v", err)
+	}
+	if err := closeOut(); err != nil {
+		return fmt.Errorf("error writing output: %v", err)
+	}
+	if dw, ok := w.(*DirWriter); ok && dryRun {
+		for _, name := range dw.Removed() {
+			fmt.Printf("would remove %s\n", filepath.Join(out, filepath.FromSlash(name)))
+		}
+	}
 	return nil
 }
+
+// RunWatch is a subcommand `mdsplit watch` -- Split a Markdown file again whenever it changes
+//
+// Flags:
+//   in:                --in                  (default: "")       Markdown input file
+//   out:               --out                 (default: ".")      Output directory for the split files
+//   interval:          --interval            (default: "500ms")  How often to check the files for changes
+//   maxHeight:         --max-height          (default: 0)        Maximum height of a slide in lines. Overridden by template selection.
+//   maxWidth:          --max-width           (default: 0)        Maximum width of a slide in pixels. Overridden by template selection.
+//   theme:             --theme               (default: "light")  light or dark
+//   templateSize:      --template-size       (default: "")       Predefined template size.
+//   fontSize:          --font-size           (default: 12)       Font size in points
+//   dpi:               --dpi                 (default: 96)       DPI for rendering
+//   fontFile:          --font-file           (default: "")       TrueType/OpenType font to measure text with
+//   monoFontFile:      --mono-font-file      (default: "")       Font to measure code with
+//   headingBreakLevel: --heading-break-level (default: 0)        Start a new slide at headings of this level or above
+//   keepWithNext:      --keep-with-next      (default: false)    Never leave a heading at the bottom of a slide
+//   splitBySection:    --split-by-section    (default: false)    One slide per section regardless of slide height
+//   breakOn:           --break-on            (default: "")       Markers forcing a slide break: hr and/or comment
+//   listNote:          --list-note           (default: false)    Add a continuation note to each part of a split list
+//   minBefore:         --min-lines-before    (default: 0)        Fewest lines of a split block left before a slide break
+//   minAfter:          --min-lines-after     (default: 0)        Fewest lines of a split block carried past a slide break
+//   strategy:          --strategy            (default: "greedy") How content is shared between slides: greedy/balanced/optimal
+//   keyColumn:         --table-key-column    (default: 0)        Column repeated on each part of a table split by width
+//   notes:             --notes               (default: "")       Further kinds of split block to note: code/paragraph/blockquote
+//   noteLang:          --note-lang           (default: "en")     Language of the continuation notes: en/de/ja
+//   noteTemplates:     --note-templates      (default: "")       JSON file of note templates by block kind
+//   breadcrumb:        --breadcrumb          (default: false)    Repeat the headings above a section atop each slide continuing it
+//   frontMatter:       --front-matter        (default: false)    Prepend YAML front matter describing each slide
+//   manifest:          --manifest            (default: false)    Write manifest.json describing the slides
+//   index:             --index               (default: false)    Write index.md linking to the slides
+//   nameTemplate:      --name-template       (default: "")       Go template naming the slide files
+//   stableNames:       --stable-names        (default: false)    Name slides by stable IDs from their section headings
+//
+// The input, and the local files its links and images point to, are checked
+// every interval, such as 500ms or 2s. Only the slides whose content changed
+// are rewritten and slides no longer produced are removed. Each split prints
+// a line comparing its slides with those of the split before, and runs until
+// interrupted. The flags are those of mdsplit, but the slides are always
+// written to the out directory, so there is no format, and they are not
+// cleaned up after other runs.
+func RunWatch(in string, out string, interval string, maxHeight int, maxWidth int, theme string, templateSize string, fontSize int, dpi int, fontFile string, monoFontFile string, headingBreakLevel int, keepWithNext bool, splitBySection bool, breakOn string, listNote bool, minBefore int, minAfter int, strategy string, keyColumn int, notes string, noteLang string, noteTemplates string, breadcrumb bool, frontMatter bool, manifest bool, index bool, nameTemplate string, stableNames bool) error {
+	if in == "" {
+		return fmt.Errorf("watching needs an input file")
+	}
+	every, err := time.ParseDuration(interval)
+	if err != nil || every <= 0 {
+		return fmt.Errorf("invalid interval %q", interval)
+	}
+	markers, err := ParseBreakMarkers(breakOn)
+	if err != nil {
+		return err
+	}
+	if err := checkStrategy(Strategy(strategy)); err != nil {
+		return err
+	}
+	nameTemplate, err = slideNameTemplate(nameTemplate, stableNames)
+	if err != nil {
+		return err
+	}
+	noteKinds, err := ParseNoteKinds(notes)
+	if err != nil {
+		return err
+	}
+	templates, err := readNoteTemplates(noteTemplates)
+	if err != nil {
+		return err
+	}
+
+	opts := SplitOptions{
+		OutDir:       out,
+		MaxHeight:    maxHeight,
+		MaxWidth:     maxWidth,
+		Theme:        theme,
+		TemplateSize: TemplateSize(templateSize),
+		FontSize:     fontSize,
+		DPI:          dpi,
+		FontFile:     fontFile,
+		MonoFontFile: monoFontFile,
+
+		HeadingBreakLevel:   headingBreakLevel,
+		KeepHeadingWithNext: keepWithNext,
+		SplitBySection:      splitBySection,
+		BreakOn:             markers,
+
+		ListContinuationNote: listNote,
+
+		MinLinesBeforeBreak: minBefore,
+		MinLinesAfterBreak:  minAfter,
+
+		Strategy:       Strategy(strategy),
+		TableKeyColumn: keyColumn,
+
+		ContinuationNotes: noteKinds,
+		NoteTemplates:     templates,
+		NoteLanguage:      noteLang,
+		HeadingBreadcrumb: breadcrumb,
+
+		SlideFrontMatter: frontMatter,
+		SourceName:       in,
+		Manifest:         manifest,
+		Index:            index,
+		NameTemplate:     nameTemplate,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	return Watch(ctx, in, opts, WatchOptions{
+		Interval: every,
+		Report: func(change SplitChange) {
+			fmt.Printf("%s %s: %s\n", time.Now().Format("15:04:05"), in, change)
+		},
+	})
+}
+
+// readNoteTemplates reads the JSON file of note templates by block kind
+// called name, or returns none when name is empty.
+func readNoteTemplates(name string) (map[string]string, error) {
+	if name == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("error reading note templates: %v", err)
+	}
+	var templates map[string]string
+	if err := json.Unmarshal(data, &templates); err != nil {
+		return nil, fmt.Errorf("error parsing note templates: %v", err)
+	}
+	return templates, nil
+}
+
+// slideNameTemplate returns the template naming the slide files, which is
+// StableNameTemplate for stable names.
+func slideNameTemplate(nameTemplate string, stableNames bool) (string, error) {
+	if !stableNames {
+		return nameTemplate, nil
+	}
+	if nameTemplate != "" {
+		return "", fmt.Errorf("stable names and a name template cannot be used together")
+	}
+	return StableNameTemplate, nil
+}
+
+// newFormatWriter returns the SlideWriter for the named output format, along
+// with a function closing the underlying output file. Only the dir format
+// cleans up after earlier runs.
+func newFormatWriter(format string, out string, clean CleanMode, dryRun bool) (SlideWriter, func() error, error) {
+	if format != "" && format != "dir" && (clean != CleanNone || dryRun) {
+		return nil, nil, fmt.Errorf("cleaning up needs the dir format")
+	}
+	var newWriter func(io.Writer) SlideWriter
+	switch format {
+	case "", "dir":
+		w, err := NewCleaningDirWriter(out, clean, dryRun)
+		if err != nil {
+			return nil, nil, err
+		}
+		return w, w.Discard, nil
+	case "zip":
+		newWriter = func(f io.Writer) SlideWriter { return NewZipWriter(f) }
+	case "tar":
+		newWriter = func(f io.Writer) SlideWriter { return NewTarWriter(f) }
+	case "stream":
+		newWriter = func(f io.Writer) SlideWriter { return NewStreamWriter(f) }
+	default:
+		return nil, nil, fmt.Errorf("unknown format %q", format)
+	}
+
+	if out == "-" {
+		return newWriter(os.Stdout), func() error { return nil }, nil
+	}
+	f, err := os.Create(out)
+	if err != nil {
+		return nil, nil, err
+	}
+	return newWriter(f), f.Close, nil
+}
@@ -50,18 +50,46 @@ func executeUsage(out io.Writer, templateName string, data interface{}) error {
 
 type RootCmd struct {
 	*flag.FlagSet
-	Commands     map[string]Cmd
-	Version      string
-	Commit       string
-	Date         string
-	in           string
-	out          string
-	maxHeight    int
-	maxWidth     int
-	theme        string
-	templateSize string
-	fontSize     int
-	dpi          int
+	Commands          map[string]Cmd
+	Version           string
+	Commit            string
+	Date              string
+	in                string
+	out               string
+	maxHeight         int
+	maxWidth          int
+	theme             string
+	templateSize      string
+	fontSize          int
+	dpi               int
+	format            string
+	fontFile          string
+	monoFontFile      string
+	headingBreakLevel int
+	keepWithNext      bool
+	splitBySection    bool
+	breakOn           string
+	listNote          bool
+	minBefore         int
+	minAfter          int
+	strategy          string
+	keyColumn         int
+	notes             string
+	noteLang          string
+	noteTemplates     string
+	breadcrumb        bool
+	frontMatter       bool
+	manifest          bool
+	index             bool
+	nameTemplate      string
+	stableNames       bool
+	clean             string
+	dryRun            bool
+	jobs              int
+	join              bool
+	documentBreaks    bool
+	titleSlides       bool
+	inputs            []string
 }
 
 func (c *RootCmd) Usage() {
@@ -77,6 +105,7 @@ func (c *RootCmd) UsageRecursive() {
 	fmt.Fprintf(os.Stderr, "Usage of %s:\n", os.Args[0])
 	c.FlagSet.PrintDefaults()
 	fmt.Fprintln(os.Stderr, "  Commands:")
+	fmt.Fprintf(os.Stderr, "    %s\n", "watch")
 }
 
 func NewRoot(name, version, commit, date string) (*RootCmd, error) {
@@ -89,7 +118,7 @@ func NewRoot(name, version, commit, date string) (*RootCmd, error) {
 	}
 	c.FlagSet.Usage = c.Usage
 
-	c.StringVar(&c.in, "in", "", "Markdown input file or stdin when empty")
+	c.StringVar(&c.in, "in", "", "Markdown input file or directory or glob pattern; stdin when no inputs are given")
 
 	c.StringVar(&c.out, "out", ".", "Output directory for the split files")
 
@@ -104,6 +133,61 @@ func NewRoot(name, version, commit, date string) (*RootCmd, error) {
 	c.IntVar(&c.fontSize, "font-size", 12, "Font size in points")
 
 	c.IntVar(&c.dpi, "dpi", 96, "DPI for rendering")
+
+	c.StringVar(&c.format, "format", "dir", "Output format: dir/zip/tar/stream")
+
+	c.StringVar(&c.fontFile, "font-file", "", "TrueType/OpenType font to measure text with")
+
+	c.StringVar(&c.monoFontFile, "mono-font-file", "", "Font to measure code with")
+
+	c.IntVar(&c.headingBreakLevel, "heading-break-level", 0, "Start a new slide at headings of this level or above")
+
+	c.BoolVar(&c.keepWithNext, "keep-with-next", false, "Never leave a heading at the bottom of a slide")
+
+	c.BoolVar(&c.splitBySection, "split-by-section", false, "One slide per section regardless of slide height")
+
+	c.StringVar(&c.breakOn, "break-on", "", "Markers forcing a slide break: hr and/or comment")
+
+	c.BoolVar(&c.listNote, "list-note", false, "Add a continuation note to each part of a split list")
+
+	c.IntVar(&c.minBefore, "min-lines-before", 0, "Fewest lines of a split block left before a slide break")
+
+	c.IntVar(&c.minAfter, "min-lines-after", 0, "Fewest lines of a split block carried past a slide break")
+
+	c.StringVar(&c.strategy, "strategy", "greedy", "How content is shared between slides: greedy/balanced/optimal")
+
+	c.IntVar(&c.keyColumn, "table-key-column", 0, "Column repeated on each part of a table split by width")
+
+	c.StringVar(&c.notes, "notes", "", "Further kinds of split block to note: code/paragraph/blockquote")
+
+	c.StringVar(&c.noteLang, "note-lang", "en", "Language of the continuation notes: en/de/ja")
+
+	c.StringVar(&c.noteTemplates, "note-templates", "", "JSON file of note templates by block kind")
+
+	c.BoolVar(&c.breadcrumb, "breadcrumb", false, "Repeat the headings above a section atop each slide continuing it")
+
+	c.BoolVar(&c.frontMatter, "front-matter", false, "Prepend YAML front matter describing each slide")
+
+	c.BoolVar(&c.manifest, "manifest", false, "Write manifest.json describing the slides")
+
+	c.BoolVar(&c.index, "index", false, "Write index.md linking to the slides")
+
+	c.StringVar(&c.nameTemplate, "name-template", "", "Go template naming the slide files")
+
+	c.BoolVar(&c.stableNames, "stable-names", false, "Name slides by stable IDs from their section headings")
+
+	c.StringVar(&c.clean, "clean", "", "Remove stale slides of an earlier run: swap/manifest")
+
+	c.BoolVar(&c.dryRun, "dry-run", false, "List the stale files that cleaning would remove and write nothing")
+
+	c.IntVar(&c.jobs, "jobs", 0, "Number of input files split at once or 0 for one per CPU")
+
+	c.BoolVar(&c.join, "join", false, "Split all inputs as one deck numbered in a single sequence")
+
+	c.BoolVar(&c.documentBreaks, "document-breaks", false, "With join start a new slide at each input file")
+
+	c.BoolVar(&c.titleSlides, "title-slides", false, "With join open each input file with a slide of its title")
+	c.Commands["watch"] = c.NewWatch()
 	c.Commands["help"] = &InternalCommand{
 		Exec: func(args []string) error {
 			for _, arg := range args {
@@ -152,8 +236,17 @@ func (c *RootCmd) Execute(args []string) error {
 			return cmd.Execute(remainingArgs[1:])
 		}
 	}
+	// Handle vararg inputs
+	{
+		varArgStart := 0
+		if varArgStart > len(remainingArgs) {
+			varArgStart = len(remainingArgs)
+		}
+		varArgs := remainingArgs[varArgStart:]
+		c.inputs = varArgs
+	}
 
-	if err := mdsplit.Run(c.in, c.out, c.maxHeight, c.maxWidth, c.theme, c.templateSize, c.fontSize, c.dpi); err != nil {
+	if err := mdsplit.Run(c.in, c.out, c.maxHeight, c.maxWidth, c.theme, c.templateSize, c.fontSize, c.dpi, c.format, c.fontFile, c.monoFontFile, c.headingBreakLevel, c.keepWithNext, c.splitBySection, c.breakOn, c.listNote, c.minBefore, c.minAfter, c.strategy, c.keyColumn, c.notes, c.noteLang, c.noteTemplates, c.breadcrumb, c.frontMatter, c.manifest, c.index, c.nameTemplate, c.stableNames, c.clean, c.dryRun, c.jobs, c.join, c.documentBreaks, c.titleSlides, c.inputs...); err != nil {
 		return fmt.Errorf("mdsplit failed: %w", err)
 	}
 	return nil
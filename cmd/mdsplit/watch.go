@@ -0,0 +1,555 @@
+// Generated by github.com/arran4/go-subcommand/cmd/gosubc
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/arran4/mdsplit"
+)
+
+var _ Cmd = (*Watch)(nil)
+
+type Watch struct {
+	*RootCmd
+	Flags             *flag.FlagSet
+	in                string
+	out               string
+	interval          string
+	maxHeight         int
+	maxWidth          int
+	theme             string
+	templateSize      string
+	fontSize          int
+	dpi               int
+	fontFile          string
+	monoFontFile      string
+	headingBreakLevel int
+	keepWithNext      bool
+	splitBySection    bool
+	breakOn           string
+	listNote          bool
+	minBefore         int
+	minAfter          int
+	strategy          string
+	keyColumn         int
+	notes             string
+	noteLang          string
+	noteTemplates     string
+	breadcrumb        bool
+	frontMatter       bool
+	manifest          bool
+	index             bool
+	nameTemplate      string
+	stableNames       bool
+	SubCommands       map[string]Cmd
+}
+
+type UsageDataWatch struct {
+	*Watch
+	Recursive bool
+}
+
+func (c *Watch) Usage() {
+	err := executeUsage(os.Stderr, "watch_usage.txt", UsageDataWatch{c, false})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error generating usage: %s\n", err)
+	}
+}
+
+func (c *Watch) UsageRecursive() {
+	err := executeUsage(os.Stderr, "watch_usage.txt", UsageDataWatch{c, true})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error generating usage: %s\n", err)
+	}
+}
+
+func (c *Watch) Execute(args []string) error {
+	if len(args) > 0 {
+		if cmd, ok := c.SubCommands[args[0]]; ok {
+			return cmd.Execute(args[1:])
+		}
+	}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			break
+		}
+		if strings.HasPrefix(arg, "-") {
+			name := arg
+			value := ""
+			hasValue := false
+			if strings.Contains(arg, "=") {
+				parts := strings.SplitN(arg, "=", 2)
+				name = parts[0]
+				value = parts[1]
+				hasValue = true
+			}
+			trimmedName := strings.TrimLeft(name, "-")
+			switch trimmedName {
+
+			case "in":
+				if !hasValue {
+					if i+1 < len(args) {
+						value = args[i+1]
+						i++
+					} else {
+						return fmt.Errorf("flag %s requires a value", name)
+					}
+				}
+				c.in = value
+
+			case "out":
+				if !hasValue {
+					if i+1 < len(args) {
+						value = args[i+1]
+						i++
+					} else {
+						return fmt.Errorf("flag %s requires a value", name)
+					}
+				}
+				c.out = value
+
+			case "interval":
+				if !hasValue {
+					if i+1 < len(args) {
+						value = args[i+1]
+						i++
+					} else {
+						return fmt.Errorf("flag %s requires a value", name)
+					}
+				}
+				c.interval = value
+
+			case "maxHeight", "max-height":
+				if !hasValue {
+					if i+1 < len(args) {
+						value = args[i+1]
+						i++
+					} else {
+						return fmt.Errorf("flag %s requires a value", name)
+					}
+				}
+				iv, err := strconv.Atoi(value)
+				if err != nil {
+					return fmt.Errorf("invalid integer value for flag %s: %s", name, value)
+				}
+				c.maxHeight = iv
+
+			case "maxWidth", "max-width":
+				if !hasValue {
+					if i+1 < len(args) {
+						value = args[i+1]
+						i++
+					} else {
+						return fmt.Errorf("flag %s requires a value", name)
+					}
+				}
+				iv, err := strconv.Atoi(value)
+				if err != nil {
+					return fmt.Errorf("invalid integer value for flag %s: %s", name, value)
+				}
+				c.maxWidth = iv
+
+			case "theme":
+				if !hasValue {
+					if i+1 < len(args) {
+						value = args[i+1]
+						i++
+					} else {
+						return fmt.Errorf("flag %s requires a value", name)
+					}
+				}
+				c.theme = value
+
+			case "templateSize", "template-size":
+				if !hasValue {
+					if i+1 < len(args) {
+						value = args[i+1]
+						i++
+					} else {
+						return fmt.Errorf("flag %s requires a value", name)
+					}
+				}
+				c.templateSize = value
+
+			case "fontSize", "font-size":
+				if !hasValue {
+					if i+1 < len(args) {
+						value = args[i+1]
+						i++
+					} else {
+						return fmt.Errorf("flag %s requires a value", name)
+					}
+				}
+				iv, err := strconv.Atoi(value)
+				if err != nil {
+					return fmt.Errorf("invalid integer value for flag %s: %s", name, value)
+				}
+				c.fontSize = iv
+
+			case "dpi":
+				if !hasValue {
+					if i+1 < len(args) {
+						value = args[i+1]
+						i++
+					} else {
+						return fmt.Errorf("flag %s requires a value", name)
+					}
+				}
+				iv, err := strconv.Atoi(value)
+				if err != nil {
+					return fmt.Errorf("invalid integer value for flag %s: %s", name, value)
+				}
+				c.dpi = iv
+
+			case "fontFile", "font-file":
+				if !hasValue {
+					if i+1 < len(args) {
+						value = args[i+1]
+						i++
+					} else {
+						return fmt.Errorf("flag %s requires a value", name)
+					}
+				}
+				c.fontFile = value
+
+			case "monoFontFile", "mono-font-file":
+				if !hasValue {
+					if i+1 < len(args) {
+						value = args[i+1]
+						i++
+					} else {
+						return fmt.Errorf("flag %s requires a value", name)
+					}
+				}
+				c.monoFontFile = value
+
+			case "headingBreakLevel", "heading-break-level":
+				if !hasValue {
+					if i+1 < len(args) {
+						value = args[i+1]
+						i++
+					} else {
+						return fmt.Errorf("flag %s requires a value", name)
+					}
+				}
+				iv, err := strconv.Atoi(value)
+				if err != nil {
+					return fmt.Errorf("invalid integer value for flag %s: %s", name, value)
+				}
+				c.headingBreakLevel = iv
+
+			case "keepWithNext", "keep-with-next":
+				if hasValue {
+					b, err := strconv.ParseBool(value)
+					if err != nil {
+						return fmt.Errorf("invalid boolean value for flag %s: %s", name, value)
+					}
+					c.keepWithNext = b
+				} else {
+					c.keepWithNext = true
+				}
+
+			case "splitBySection", "split-by-section":
+				if hasValue {
+					b, err := strconv.ParseBool(value)
+					if err != nil {
+						return fmt.Errorf("invalid boolean value for flag %s: %s", name, value)
+					}
+					c.splitBySection = b
+				} else {
+					c.splitBySection = true
+				}
+
+			case "breakOn", "break-on":
+				if !hasValue {
+					if i+1 < len(args) {
+						value = args[i+1]
+						i++
+					} else {
+						return fmt.Errorf("flag %s requires a value", name)
+					}
+				}
+				c.breakOn = value
+
+			case "listNote", "list-note":
+				if hasValue {
+					b, err := strconv.ParseBool(value)
+					if err != nil {
+						return fmt.Errorf("invalid boolean value for flag %s: %s", name, value)
+					}
+					c.listNote = b
+				} else {
+					c.listNote = true
+				}
+
+			case "minBefore", "min-lines-before":
+				if !hasValue {
+					if i+1 < len(args) {
+						value = args[i+1]
+						i++
+					} else {
+						return fmt.Errorf("flag %s requires a value", name)
+					}
+				}
+				iv, err := strconv.Atoi(value)
+				if err != nil {
+					return fmt.Errorf("invalid integer value for flag %s: %s", name, value)
+				}
+				c.minBefore = iv
+
+			case "minAfter", "min-lines-after":
+				if !hasValue {
+					if i+1 < len(args) {
+						value = args[i+1]
+						i++
+					} else {
+						return fmt.Errorf("flag %s requires a value", name)
+					}
+				}
+				iv, err := strconv.Atoi(value)
+				if err != nil {
+					return fmt.Errorf("invalid integer value for flag %s: %s", name, value)
+				}
+				c.minAfter = iv
+
+			case "strategy":
+				if !hasValue {
+					if i+1 < len(args) {
+						value = args[i+1]
+						i++
+					} else {
+						return fmt.Errorf("flag %s requires a value", name)
+					}
+				}
+				c.strategy = value
+
+			case "keyColumn", "table-key-column":
+				if !hasValue {
+					if i+1 < len(args) {
+						value = args[i+1]
+						i++
+					} else {
+						return fmt.Errorf("flag %s requires a value", name)
+					}
+				}
+				iv, err := strconv.Atoi(value)
+				if err != nil {
+					return fmt.Errorf("invalid integer value for flag %s: %s", name, value)
+				}
+				c.keyColumn = iv
+
+			case "notes":
+				if !hasValue {
+					if i+1 < len(args) {
+						value = args[i+1]
+						i++
+					} else {
+						return fmt.Errorf("flag %s requires a value", name)
+					}
+				}
+				c.notes = value
+
+			case "noteLang", "note-lang":
+				if !hasValue {
+					if i+1 < len(args) {
+						value = args[i+1]
+						i++
+					} else {
+						return fmt.Errorf("flag %s requires a value", name)
+					}
+				}
+				c.noteLang = value
+
+			case "noteTemplates", "note-templates":
+				if !hasValue {
+					if i+1 < len(args) {
+						value = args[i+1]
+						i++
+					} else {
+						return fmt.Errorf("flag %s requires a value", name)
+					}
+				}
+				c.noteTemplates = value
+
+			case "breadcrumb":
+				if hasValue {
+					b, err := strconv.ParseBool(value)
+					if err != nil {
+						return fmt.Errorf("invalid boolean value for flag %s: %s", name, value)
+					}
+					c.breadcrumb = b
+				} else {
+					c.breadcrumb = true
+				}
+
+			case "frontMatter", "front-matter":
+				if hasValue {
+					b, err := strconv.ParseBool(value)
+					if err != nil {
+						return fmt.Errorf("invalid boolean value for flag %s: %s", name, value)
+					}
+					c.frontMatter = b
+				} else {
+					c.frontMatter = true
+				}
+
+			case "manifest":
+				if hasValue {
+					b, err := strconv.ParseBool(value)
+					if err != nil {
+						return fmt.Errorf("invalid boolean value for flag %s: %s", name, value)
+					}
+					c.manifest = b
+				} else {
+					c.manifest = true
+				}
+
+			case "index":
+				if hasValue {
+					b, err := strconv.ParseBool(value)
+					if err != nil {
+						return fmt.Errorf("invalid boolean value for flag %s: %s", name, value)
+					}
+					c.index = b
+				} else {
+					c.index = true
+				}
+
+			case "nameTemplate", "name-template":
+				if !hasValue {
+					if i+1 < len(args) {
+						value = args[i+1]
+						i++
+					} else {
+						return fmt.Errorf("flag %s requires a value", name)
+					}
+				}
+				c.nameTemplate = value
+
+			case "stableNames", "stable-names":
+				if hasValue {
+					b, err := strconv.ParseBool(value)
+					if err != nil {
+						return fmt.Errorf("invalid boolean value for flag %s: %s", name, value)
+					}
+					c.stableNames = b
+				} else {
+					c.stableNames = true
+				}
+			case "help", "h":
+				c.Usage()
+				return nil
+			default:
+				return fmt.Errorf("unknown flag: %s", name)
+			}
+		}
+	}
+
+	if err := mdsplit.RunWatch(c.in, c.out, c.interval, c.maxHeight, c.maxWidth, c.theme, c.templateSize, c.fontSize, c.dpi, c.fontFile, c.monoFontFile, c.headingBreakLevel, c.keepWithNext, c.splitBySection, c.breakOn, c.listNote, c.minBefore, c.minAfter, c.strategy, c.keyColumn, c.notes, c.noteLang, c.noteTemplates, c.breadcrumb, c.frontMatter, c.manifest, c.index, c.nameTemplate, c.stableNames); err != nil {
+		return fmt.Errorf("watch failed: %w", err)
+	}
+
+	return nil
+}
+
+func (c *RootCmd) NewWatch() *Watch {
+	set := flag.NewFlagSet("watch", flag.ContinueOnError)
+	v := &Watch{
+		RootCmd:     c,
+		Flags:       set,
+		SubCommands: make(map[string]Cmd),
+	}
+
+	set.StringVar(&v.in, "in", "", "Markdown input file")
+
+	set.StringVar(&v.out, "out", ".", "Output directory for the split files")
+
+	set.StringVar(&v.interval, "interval", "500ms", "How often to check the files for changes")
+
+	set.IntVar(&v.maxHeight, "max-height", 0, "Maximum height of a slide in lines. Overridden by template selection.")
+
+	set.IntVar(&v.maxWidth, "max-width", 0, "Maximum width of a slide in pixels. Overridden by template selection.")
+
+	set.StringVar(&v.theme, "theme", "light", "light or dark")
+
+	set.StringVar(&v.templateSize, "template-size", "", "Predefined template size.")
+
+	set.IntVar(&v.fontSize, "font-size", 12, "Font size in points")
+
+	set.IntVar(&v.dpi, "dpi", 96, "DPI for rendering")
+
+	set.StringVar(&v.fontFile, "font-file", "", "TrueType/OpenType font to measure text with")
+
+	set.StringVar(&v.monoFontFile, "mono-font-file", "", "Font to measure code with")
+
+	set.IntVar(&v.headingBreakLevel, "heading-break-level", 0, "Start a new slide at headings of this level or above")
+
+	set.BoolVar(&v.keepWithNext, "keep-with-next", false, "Never leave a heading at the bottom of a slide")
+
+	set.BoolVar(&v.splitBySection, "split-by-section", false, "One slide per section regardless of slide height")
+
+	set.StringVar(&v.breakOn, "break-on", "", "Markers forcing a slide break: hr and/or comment")
+
+	set.BoolVar(&v.listNote, "list-note", false, "Add a continuation note to each part of a split list")
+
+	set.IntVar(&v.minBefore, "min-lines-before", 0, "Fewest lines of a split block left before a slide break")
+
+	set.IntVar(&v.minAfter, "min-lines-after", 0, "Fewest lines of a split block carried past a slide break")
+
+	set.StringVar(&v.strategy, "strategy", "greedy", "How content is shared between slides: greedy/balanced/optimal")
+
+	set.IntVar(&v.keyColumn, "table-key-column", 0, "Column repeated on each part of a table split by width")
+
+	set.StringVar(&v.notes, "notes", "", "Further kinds of split block to note: code/paragraph/blockquote")
+
+	set.StringVar(&v.noteLang, "note-lang", "en", "Language of the continuation notes: en/de/ja")
+
+	set.StringVar(&v.noteTemplates, "note-templates", "", "JSON file of note templates by block kind")
+
+	set.BoolVar(&v.breadcrumb, "breadcrumb", false, "Repeat the headings above a section atop each slide continuing it")
+
+	set.BoolVar(&v.frontMatter, "front-matter", false, "Prepend YAML front matter describing each slide")
+
+	set.BoolVar(&v.manifest, "manifest", false, "Write manifest.json describing the slides")
+
+	set.BoolVar(&v.index, "index", false, "Write index.md linking to the slides")
+
+	set.StringVar(&v.nameTemplate, "name-template", "", "Go template naming the slide files")
+
+	set.BoolVar(&v.stableNames, "stable-names", false, "Name slides by stable IDs from their section headings")
+	set.Usage = v.Usage
+
+	v.SubCommands["help"] = &InternalCommand{
+		Exec: func(args []string) error {
+			for _, arg := range args {
+				if arg == "-deep" {
+					v.UsageRecursive()
+					return nil
+				}
+			}
+			v.Usage()
+			return nil
+		},
+		UsageFunc: v.Usage,
+	}
+	v.SubCommands["usage"] = &InternalCommand{
+		Exec: func(args []string) error {
+			for _, arg := range args {
+				if arg == "-deep" {
+					v.UsageRecursive()
+					return nil
+				}
+			}
+			v.Usage()
+			return nil
+		},
+		UsageFunc: v.Usage,
+	}
+	return v
+}
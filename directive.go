@@ -0,0 +1,69 @@
+package mdsplit
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+)
+
+// BreakMarker selects which Markdown constructs force a slide break.
+type BreakMarker int
+
+const (
+	// BreakOnThematicBreak breaks at thematic breaks (---, ***, ___).
+	BreakOnThematicBreak BreakMarker = 1 << iota
+	// BreakOnComment breaks at <!-- mdsplit:break --> comments.
+	BreakOnComment
+)
+
+// ParseBreakMarkers parses a comma separated list of break markers: "hr" for
+// thematic breaks and "comment" for <!-- mdsplit:break --> comments.
+func ParseBreakMarkers(s string) (BreakMarker, error) {
+	var markers BreakMarker
+	for _, name := range strings.Split(s, ",") {
+		switch strings.TrimSpace(name) {
+		case "":
+		case "hr":
+			markers |= BreakOnThematicBreak
+		case "comment":
+			markers |= BreakOnComment
+		default:
+			return 0, fmt.Errorf("unknown break marker %q", name)
+		}
+	}
+	return markers, nil
+}
+
+// directivePattern matches an HTML comment holding an mdsplit directive.
+var directivePattern = regexp.MustCompile(`^<!--\s*mdsplit:([a-z-]+)\s*-->$`)
+
+// directive returns the name of the mdsplit directive n holds, such as
+// "break" for <!-- mdsplit:break -->, or "" if n is not a directive.
+func directive(n ast.Node, source []byte) string {
+	if n.Kind() != ast.KindHTMLBlock {
+		return ""
+	}
+	var b strings.Builder
+	lines := n.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		segment := lines.At(i)
+		b.Write(segment.Value(source))
+	}
+	if m := directivePattern.FindStringSubmatch(strings.TrimSpace(b.String())); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// isBreakMarker reports whether n is a break marker honoured by markers.
+func isBreakMarker(n ast.Node, source []byte, markers BreakMarker) bool {
+	switch {
+	case n.Kind() == ast.KindThematicBreak:
+		return markers&BreakOnThematicBreak != 0
+	case markers&BreakOnComment != 0:
+		return directive(n, source) == "break"
+	}
+	return false
+}
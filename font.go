@@ -0,0 +1,135 @@
+package mdsplit
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// FontMeasurer measures text with the glyph advances and kerning of real
+// TrueType or OpenType fonts, such as the ones md2png renders with. Runes the
+// fonts have no glyph for are measured by Fallback.
+type FontMeasurer struct {
+	Regular  *sfnt.Font // Face for proportional text
+	Mono     *sfnt.Font // Face for code, or nil to use Regular
+	Fallback Measurer   // Measures runes missing from the fonts
+
+	mu  sync.Mutex
+	buf sfnt.Buffer
+}
+
+// LoadFontMeasurer reads the font files at regularPath and, if not empty,
+// monoPath. Font collections (.ttc, .otc) use their first font.
+func LoadFontMeasurer(regularPath, monoPath string) (*FontMeasurer, error) {
+	regular, err := loadFont(regularPath)
+	if err != nil {
+		return nil, err
+	}
+	m := &FontMeasurer{Regular: regular, Fallback: AverageWidthMeasurer{}}
+	if monoPath != "" {
+		if m.Mono, err = loadFont(monoPath); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// NewFontMeasurer parses the given font data. mono may be nil.
+func NewFontMeasurer(regular, mono []byte) (*FontMeasurer, error) {
+	m := &FontMeasurer{Fallback: AverageWidthMeasurer{}}
+	var err error
+	if m.Regular, err = parseFont(regular); err != nil {
+		return nil, err
+	}
+	if mono != nil {
+		if m.Mono, err = parseFont(mono); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func loadFont(path string) (*sfnt.Font, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := parseFont(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing font %s: %w", path, err)
+	}
+	return f, nil
+}
+
+func parseFont(data []byte) (*sfnt.Font, error) {
+	f, err := sfnt.Parse(data)
+	if err == nil {
+		return f, nil
+	}
+	c, cErr := sfnt.ParseCollection(data)
+	if cErr != nil {
+		return nil, err
+	}
+	return c.Font(0)
+}
+
+func (m *FontMeasurer) TextWidth(s string, size float64, mono bool) float64 {
+	f := m.Regular
+	if mono && m.Mono != nil {
+		f = m.Mono
+	}
+	ppem := fixed.Int26_6(size * 64)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var total fixed.Int26_6
+	var missing float64
+	var prev sfnt.GlyphIndex
+	for _, r := range s {
+		if r == '\t' {
+			r = ' '
+			total += 3 * m.advance(f, ' ', ppem)
+		}
+		idx, err := f.GlyphIndex(&m.buf, r)
+		if err != nil || idx == 0 {
+			missing += m.fallback().TextWidth(string(r), size, mono)
+			prev = 0
+			continue
+		}
+		if prev != 0 {
+			if kern, err := f.Kern(&m.buf, prev, idx, ppem, font.HintingNone); err == nil {
+				total += kern
+			}
+		}
+		if adv, err := f.GlyphAdvance(&m.buf, idx, ppem, font.HintingNone); err == nil {
+			total += adv
+		}
+		prev = idx
+	}
+	return float64(total)/64 + missing
+}
+
+// advance returns the advance of r alone, or 0 if the font has no glyph for it.
+func (m *FontMeasurer) advance(f *sfnt.Font, r rune, ppem fixed.Int26_6) fixed.Int26_6 {
+	idx, err := f.GlyphIndex(&m.buf, r)
+	if err != nil || idx == 0 {
+		return 0
+	}
+	adv, err := f.GlyphAdvance(&m.buf, idx, ppem, font.HintingNone)
+	if err != nil {
+		return 0
+	}
+	return adv
+}
+
+func (m *FontMeasurer) fallback() Measurer {
+	if m.Fallback == nil {
+		return AverageWidthMeasurer{}
+	}
+	return m.Fallback
+}
@@ -0,0 +1,50 @@
+package mdsplit
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+func TestFontMeasurer(t *testing.T) {
+	m, err := NewFontMeasurer(goregular.TTF, gomono.TTF)
+	if err != nil {
+		t.Fatalf("NewFontMeasurer failed: %v", err)
+	}
+
+	narrow := m.TextWidth("iiii", 16, false)
+	wide := m.TextWidth("MMMM", 16, false)
+	if narrow >= wide {
+		t.Errorf("Expected iiii (%v) to be narrower than MMMM (%v) in a proportional font", narrow, wide)
+	}
+	if a, b := m.TextWidth("iiii", 16, true), m.TextWidth("MMMM", 16, true); a != b {
+		t.Errorf("Expected equal widths in a monospaced font, got %v and %v", a, b)
+	}
+
+	// Go fonts have no CJK glyphs, so those fall back to average widths.
+	if got, expected := m.TextWidth("日本", 16, false), (AverageWidthMeasurer{}).TextWidth("日本", 16, false); got != expected {
+		t.Errorf("Expected fallback width %v for missing glyphs, got %v", expected, got)
+	}
+}
+
+func TestSplitWithMeasurer(t *testing.T) {
+	m, err := NewFontMeasurer(goregular.TTF, nil)
+	if err != nil {
+		t.Fatalf("NewFontMeasurer failed: %v", err)
+	}
+	// Narrow glyphs fit far more per line than the average width estimate allows.
+	input := []byte("Title\n\n" + strings.Repeat("ill ", 60) + "\n")
+	average, err := SplitSlides(input, SplitOptions{MaxHeight: 4, MaxWidth: 300})
+	if err != nil {
+		t.Fatalf("SplitSlides failed: %v", err)
+	}
+	measured, err := SplitSlides(input, SplitOptions{MaxHeight: 4, MaxWidth: 300, Measurer: m})
+	if err != nil {
+		t.Fatalf("SplitSlides failed: %v", err)
+	}
+	if len(measured) >= len(average) {
+		t.Errorf("Expected fewer slides with real glyph widths, got %d measured and %d average", len(measured), len(average))
+	}
+}
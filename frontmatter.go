@@ -0,0 +1,275 @@
+package mdsplit
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// frontMatter is the metadata block opening a document.
+type frontMatter struct {
+	format string // "yaml" or "toml"
+	raw    []byte // Metadata between the delimiter lines
+	end    int    // Byte offset just past the closing delimiter line
+}
+
+// findFrontMatter returns the YAML front matter, between --- lines, or the
+// TOML front matter, between +++ lines, opening data. A YAML block may also
+// close with a ... line. Since --- is also a thematic break, the block only
+// counts when it starts on the line after the opening delimiter and decodes
+// to a mapping; anything else is left to be read as Markdown.
+func findFrontMatter(data []byte) (frontMatter, bool) {
+	line, next := nextLine(data, 0)
+	var format string
+	var closers []string
+	switch line {
+	case "---":
+		format, closers = "yaml", []string{"---", "..."}
+	case "+++":
+		format, closers = "toml", []string{"+++"}
+	default:
+		return frontMatter{}, false
+	}
+	if first, _ := nextLine(data, next); first == "" {
+		return frontMatter{}, false
+	}
+	for pos := next; pos < len(data); {
+		line, end := nextLine(data, pos)
+		if slices.Contains(closers, line) {
+			fm := frontMatter{format: format, raw: data[next:pos], end: end}
+			if !fm.isMapping() {
+				return frontMatter{}, false
+			}
+			return fm, true
+		}
+		pos = end
+	}
+	return frontMatter{}, false
+}
+
+// isMapping reports whether the front matter decodes to a mapping with at
+// least one key.
+func (fm frontMatter) isMapping() bool {
+	var m map[string]any
+	var err error
+	switch fm.format {
+	case "yaml":
+		err = yaml.Unmarshal(fm.raw, &m)
+	case "toml":
+		_, err = toml.Decode(string(fm.raw), &m)
+	}
+	return err == nil && len(m) > 0
+}
+
+// nextLine returns the line starting at pos, without trailing white space,
+// and the offset of the line after it.
+func nextLine(data []byte, pos int) (string, int) {
+	end := len(data)
+	if i := bytes.IndexByte(data[pos:], '\n'); i >= 0 {
+		end = pos + i + 1
+	}
+	return strings.TrimRight(string(data[pos:end]), " \t\r\n"), end
+}
+
+// options decodes the table under the mdsplit key of the front matter.
+func (fm frontMatter) options() (map[string]any, error) {
+	var meta struct {
+		Mdsplit map[string]any `yaml:"mdsplit" toml:"mdsplit"`
+	}
+	var err error
+	switch fm.format {
+	case "yaml":
+		err = yaml.Unmarshal(fm.raw, &meta)
+	case "toml":
+		_, err = toml.Decode(string(fm.raw), &meta)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s front matter: %w", fm.format, err)
+	}
+	return meta.Mdsplit, nil
+}
+
+// title returns the title the front matter gives the document, if any.
+func (fm frontMatter) title() string {
+	var meta struct {
+		Title string `yaml:"title" toml:"title"`
+	}
+	switch fm.format {
+	case "yaml":
+		_ = yaml.Unmarshal(fm.raw, &meta)
+	case "toml":
+		_, _ = toml.Decode(string(fm.raw), &meta)
+	}
+	return meta.Title
+}
+
+// blank returns a copy of data with the front matter replaced by blank lines,
+// so that it is left out of the slides while byte offsets still point into
+// data.
+func (fm frontMatter) blank(data []byte) []byte {
+	data = bytes.Clone(data)
+	for i := range data[:fm.end] {
+		if data[i] != '\n' {
+			data[i] = ' '
+		}
+	}
+	return data
+}
+
+// readFrontMatter applies the options set in the front matter opening data,
+// if any, to opts, and returns data with the front matter blanked out.
+func readFrontMatter(data []byte, opts *SplitOptions) ([]byte, error) {
+	fm, ok := findFrontMatter(data)
+	if !ok {
+		return data, nil
+	}
+	m, err := fm.options()
+	if err != nil {
+		return nil, err
+	}
+	if err := applyDocumentOptions(opts, m); err != nil {
+		return nil, err
+	}
+	return fm.blank(data), nil
+}
+
+// applyDocumentOptions overrides opts with the options a document sets in its
+// front matter, named as the CLI flags are. A max-height or max-width set
+// without a template-size replaces the template size given in opts.
+func applyDocumentOptions(opts *SplitOptions, m map[string]any) error {
+	for key, v := range m {
+		var err error
+		switch key {
+		case "template-size":
+			var size string
+			size, err = stringOption(key, v)
+			opts.TemplateSize = TemplateSize(size)
+		case "max-height":
+			opts.MaxHeight, err = intOption(key, v)
+		case "max-width":
+			opts.MaxWidth, err = intOption(key, v)
+		case "theme":
+			opts.Theme, err = stringOption(key, v)
+		case "heading-break-level":
+			opts.HeadingBreakLevel, err = intOption(key, v)
+		case "keep-with-next":
+			opts.KeepHeadingWithNext, err = boolOption(key, v)
+		case "split-by-section":
+			opts.SplitBySection, err = boolOption(key, v)
+		case "break-on":
+			opts.BreakOn, err = breakOnOption(key, v)
+		case "strategy":
+			var strategy string
+			strategy, err = stringOption(key, v)
+			opts.Strategy = Strategy(strategy)
+		default:
+			err = fmt.Errorf("unknown mdsplit option %q", key)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	_, height := m["max-height"]
+	_, width := m["max-width"]
+	if _, size := m["template-size"]; (height || width) && !size {
+		opts.TemplateSize = ""
+	}
+	return nil
+}
+
+// stringOption returns v as a string.
+func stringOption(key string, v any) (string, error) {
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+	return "", fmt.Errorf("mdsplit option %s: expected a string, got %v", key, v)
+}
+
+// intOption returns v as an int. YAML decodes whole numbers as int and TOML
+// as int64.
+func intOption(key string, v any) (int, error) {
+	switch n := v.(type) {
+	case int:
+		return n, nil
+	case int64:
+		return int(n), nil
+	}
+	return 0, fmt.Errorf("mdsplit option %s: expected a whole number, got %v", key, v)
+}
+
+// boolOption returns v as a bool.
+func boolOption(key string, v any) (bool, error) {
+	if b, ok := v.(bool); ok {
+		return b, nil
+	}
+	return false, fmt.Errorf("mdsplit option %s: expected true or false, got %v", key, v)
+}
+
+// breakOnOption parses break markers given as a comma separated string or as
+// a list of strings.
+func breakOnOption(key string, v any) (BreakMarker, error) {
+	var names []string
+	switch v := v.(type) {
+	case string:
+		names = []string{v}
+	case []any:
+		for _, name := range v {
+			s, err := stringOption(key, name)
+			if err != nil {
+				return 0, err
+			}
+			names = append(names, s)
+		}
+	default:
+		return 0, fmt.Errorf("mdsplit option %s: expected a string or list, got %v", key, v)
+	}
+	return ParseBreakMarkers(strings.Join(names, ","))
+}
+
+// slideMeta is the front matter written at the top of each slide.
+type slideMeta struct {
+	Slide        int    `yaml:"slide"`
+	ID           string `yaml:"id"`
+	Total        int    `yaml:"total"`
+	Source       string `yaml:"source,omitempty"`
+	FirstLine    int    `yaml:"first-line,omitempty"`
+	LastLine     int    `yaml:"last-line,omitempty"`
+	Section      string `yaml:"section,omitempty"`
+	Theme        string `yaml:"theme,omitempty"`
+	TemplateSize string `yaml:"template-size,omitempty"`
+}
+
+// addSlideFrontMatter prepends YAML front matter describing each slide to its
+// content: its position and ID, the source file and 1-based lines it came
+// from, the section it begins under, and the theme and template size of the
+// deck. sources holds the text of each source file by name.
+func addSlideFrontMatter(slides []Slide, sources map[string][]byte, opts SplitOptions) error {
+	for i := range slides {
+		slide := &slides[i]
+		meta := slideMeta{
+			Slide:        slide.Index,
+			ID:           slide.ID,
+			Total:        len(slides),
+			Source:       slide.Source,
+			Section:      slide.Section,
+			Theme:        opts.Theme,
+			TemplateSize: string(opts.TemplateSize),
+		}
+		meta.FirstLine, meta.LastLine = lineRange(sources[slide.Source], slide.Start, slide.End)
+		out, err := yaml.Marshal(meta)
+		if err != nil {
+			return fmt.Errorf("writing front matter of slide %d: %w", slide.Index, err)
+		}
+		content := make([]byte, 0, len(out)+len(slide.Content)+9)
+		content = append(content, "---\n"...)
+		content = append(content, out...)
+		content = append(content, "---\n\n"...)
+		slide.Content = append(content, slide.Content...)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package mdsplit
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFindFrontMatter(t *testing.T) {
+	testCases := []struct {
+		name   string
+		input  string
+		format string
+		raw    string
+	}{
+		{name: "yaml", input: "---\ntitle: Deck\n---\n# Hi\n", format: "yaml", raw: "title: Deck\n"},
+		{name: "yaml closed by dots", input: "---\ntitle: Deck\n...\n# Hi\n", format: "yaml", raw: "title: Deck\n"},
+		{name: "toml", input: "+++\r\ntitle = \"Deck\"\r\n+++\r\n# Hi\r\n", format: "toml", raw: "title = \"Deck\"\r\n"},
+		{name: "unclosed", input: "---\ntitle: Deck\n"},
+		{name: "not at start", input: "\n---\ntitle: Deck\n---\n"},
+		{name: "thematic breaks", input: "---\n# Title\n\nSome text here.\n\n---\n"},
+		{name: "blank first line", input: "---\n\nIntro: text\n\n---\n"},
+		{name: "not yaml", input: "---\nmdsplit: [\n---\n"},
+		{name: "empty", input: "---\n---\n"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fm, ok := findFrontMatter([]byte(tc.input))
+			if ok != (tc.format != "") || fm.format != tc.format || string(fm.raw) != tc.raw {
+				t.Errorf("findFrontMatter = %q %q %v, expected %q %q", fm.format, fm.raw, ok, tc.format, tc.raw)
+			}
+		})
+	}
+}
+
+func TestDocumentOptions(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		opts     SplitOptions
+		expected SplitOptions
+		err      string
+	}{
+		{
+			name:     "yaml",
+			input:    "---\ntitle: Deck\nmdsplit:\n  max-height: 20\n  theme: dark\n  break-on: [hr, comment]\n  keep-with-next: true\n---\n",
+			opts:     SplitOptions{TemplateSize: TemplateSizeCard},
+			expected: SplitOptions{MaxHeight: 20, Theme: "dark", BreakOn: BreakOnThematicBreak | BreakOnComment, KeepHeadingWithNext: true},
+		},
+		{
+			name:     "toml",
+			input:    "+++\n[mdsplit]\ntemplate-size = \"a4\"\nheading-break-level = 2\nstrategy = \"optimal\"\n+++\n",
+			expected: SplitOptions{TemplateSize: TemplateSizeA4, HeadingBreakLevel: 2, Strategy: StrategyOptimal},
+		},
+		{
+			name:     "no mdsplit key",
+			input:    "---\ntitle: Deck\n---\n",
+			opts:     SplitOptions{MaxHeight: 10},
+			expected: SplitOptions{MaxHeight: 10},
+		},
+		{name: "unknown option", input: "---\nmdsplit:\n  height: 20\n---\n", err: `unknown mdsplit option "height"`},
+		{name: "wrong type", input: "---\nmdsplit:\n  max-height: tall\n---\n", err: "mdsplit option max-height: expected a whole number"},
+		{name: "mdsplit not a mapping", input: "---\nmdsplit: 5\n---\n", err: "parsing yaml front matter"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fm, _ := findFrontMatter([]byte(tc.input))
+			opts := tc.opts
+			m, err := fm.options()
+			if err == nil {
+				err = applyDocumentOptions(&opts, m)
+			}
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Errorf("error = %v, expected %q", err, tc.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if opts.MaxHeight != tc.expected.MaxHeight || opts.TemplateSize != tc.expected.TemplateSize || opts.Theme != tc.expected.Theme ||
+				opts.BreakOn != tc.expected.BreakOn || opts.KeepHeadingWithNext != tc.expected.KeepHeadingWithNext ||
+				opts.HeadingBreakLevel != tc.expected.HeadingBreakLevel || opts.Strategy != tc.expected.Strategy {
+				t.Errorf("options = %+v, expected %+v", opts, tc.expected)
+			}
+		})
+	}
+}
+
+func TestSlideFrontMatter(t *testing.T) {
+	input := "---\ntitle: Deck\n---\n# Intro\n\nHello.\n\n## Details\n\nMore.\n"
+	slides, err := SplitSlides([]byte(input), SplitOptions{MaxHeight: 4, Theme: "dark", SlideFrontMatter: true, SourceName: "deck.md"})
+	if err != nil {
+		t.Fatalf("SplitSlides failed: %v", err)
+	}
+	expected := []string{
+		"---\nslide: 1\nid: intro-1\ntotal: 2\nsource: deck.md\nfirst-line: 4\nlast-line: 6\nsection: Intro\ntheme: dark\n---\n\n# Intro\n\nHello.\n",
+		"---\nslide: 2\nid: details-1\ntotal: 2\nsource: deck.md\nfirst-line: 8\nlast-line: 10\nsection: Details\ntheme: dark\n---\n\n## Details\n\nMore.\n",
+	}
+	if len(slides) != len(expected) {
+		t.Fatalf("Expected %d slides, but got %d", len(expected), len(slides))
+	}
+	for i, want := range expected {
+		if got := strings.TrimSpace(string(slides[i].Content)); got != strings.TrimSpace(want) {
+			t.Errorf("Slide %d content:\n%s\nexpected:\n%s", i+1, got, want)
+		}
+	}
+}
@@ -3,12 +3,16 @@ module github.com/arran4/mdsplit
 go 1.24.3
 
 require (
+	github.com/BurntSushi/toml v1.6.0
+	github.com/rivo/uniseg v0.4.7
 	github.com/teekennedy/goldmark-markdown v0.5.1
 	github.com/yuin/goldmark v1.7.8
+	golang.org/x/image v0.34.0
+	golang.org/x/text v0.32.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/arran4/go-subcommand v0.0.12 // indirect
 	golang.org/x/mod v0.31.0 // indirect
-	golang.org/x/text v0.32.0 // indirect
 )
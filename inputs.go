@@ -0,0 +1,315 @@
+package mdsplit
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// markdownExts are the extensions of the files taken from input directories.
+var markdownExts = []string{".md", ".markdown"}
+
+// input is a Markdown file to split.
+type input struct {
+	path string // Path to read the file from
+	rel  string // Slash-separated path of the file below the input it was found by
+}
+
+// dir returns the directory the slides of the input go in, its relative path
+// without the extension.
+func (in input) dir() string {
+	return strings.TrimSuffix(in.rel, path.Ext(in.rel))
+}
+
+// expandInputs resolves files, directories, searched recursively for
+// Markdown files, and glob patterns, in which ** matches any number of
+// directories, into the files to split. When out lies below a directory or
+// the fixed part of a pattern, the files found under out are skipped, so
+// slides written into an input directory are not split again. It reports
+// whether the inputs name more than a single file. A file named twice is
+// taken once, and finding no files at all is an error.
+func expandInputs(patterns []string, out string) ([]input, bool, error) {
+	multi := len(patterns) > 1
+	var inputs []input
+	seen := map[string]bool{}
+	// skipped returns the directory whose files are skipped when searching
+	// root: out, if it lies below root, or else none.
+	skipped := func(root string) string {
+		if out == "" || !within(out, root) || within(root, out) {
+			return ""
+		}
+		return out
+	}
+	add := func(p, rel, skip string) error {
+		if skip != "" && within(p, skip) || seen[filepath.Clean(p)] {
+			return nil
+		}
+		seen[filepath.Clean(p)] = true
+		inputs = append(inputs, input{path: p, rel: rel})
+		return nil
+	}
+
+	for _, pattern := range patterns {
+		if hasMeta(pattern) {
+			multi = true
+			root, matches, err := glob(pattern)
+			if err != nil {
+				return nil, false, err
+			}
+			if len(matches) == 0 {
+				return nil, false, fmt.Errorf("no files match %s", pattern)
+			}
+			skip := skipped(root)
+			for _, p := range matches {
+				rel, err := filepath.Rel(root, p)
+				if err != nil {
+					return nil, false, err
+				}
+				if err := add(p, filepath.ToSlash(rel), skip); err != nil {
+					return nil, false, err
+				}
+			}
+			continue
+		}
+
+		info, err := os.Stat(pattern)
+		if err != nil {
+			return nil, false, err
+		}
+		if !info.IsDir() {
+			if err := add(pattern, filepath.Base(pattern), ""); err != nil {
+				return nil, false, err
+			}
+			continue
+		}
+		multi = true
+		skip := skipped(pattern)
+		err = filepath.WalkDir(pattern, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if p != pattern && strings.HasPrefix(d.Name(), ".") {
+					return filepath.SkipDir // Such as .git
+				}
+				return nil
+			}
+			if !slices.Contains(markdownExts, strings.ToLower(filepath.Ext(p))) {
+				return nil
+			}
+			rel, err := filepath.Rel(pattern, p)
+			if err != nil {
+				return err
+			}
+			return add(p, filepath.ToSlash(rel), skip)
+		})
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	if len(inputs) == 0 {
+		return nil, false, fmt.Errorf("no Markdown files found in %s", strings.Join(patterns, ", "))
+	}
+	return inputs, multi, nil
+}
+
+// hasMeta reports whether pattern holds any glob syntax.
+func hasMeta(pattern string) bool {
+	return strings.ContainsAny(pattern, `*?[`)
+}
+
+// glob returns the files matching pattern, sorted, along with the directory
+// before its first wildcard, which their paths are taken relative to.
+func glob(pattern string) (string, []string, error) {
+	parts := strings.Split(filepath.ToSlash(pattern), "/")
+	fixed := 0
+	for fixed < len(parts)-1 && !hasMeta(parts[fixed]) {
+		fixed++
+	}
+	root := filepath.FromSlash(strings.Join(parts[:fixed], "/"))
+	if root == "" {
+		root = "."
+		if strings.HasPrefix(pattern, "/") {
+			root = "/"
+		}
+	}
+	rest := parts[fixed:]
+	for _, part := range rest {
+		if _, err := path.Match(part, ""); err != nil {
+			return "", nil, fmt.Errorf("bad pattern %s: %w", pattern, err)
+		}
+	}
+
+	var matches []string
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == root && errors.Is(err, fs.ErrNotExist) {
+				return fs.SkipAll
+			}
+			return err
+		}
+		if d.IsDir() {
+			if p != root && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		if matchParts(rest, strings.Split(filepath.ToSlash(rel), "/")) {
+			matches = append(matches, p)
+		}
+		return nil
+	})
+	return root, matches, err
+}
+
+// matchParts matches the parts of a path against those of a pattern, where a
+// ** part matches any number of path parts.
+func matchParts(pattern, name []string) bool {
+	if len(pattern) == 0 {
+		return len(name) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(name); i++ {
+			if matchParts(pattern[1:], name[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(name) == 0 {
+		return false
+	}
+	ok, _ := path.Match(pattern[0], name[0])
+	return ok && matchParts(pattern[1:], name[1:])
+}
+
+// within reports whether p is dir or lies below it.
+func within(p, dir string) bool {
+	p, err1 := filepath.Abs(p)
+	dir, err2 := filepath.Abs(dir)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	rel, err := filepath.Rel(dir, p)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// splitResult is the outcome of splitting one input.
+type splitResult struct {
+	slides []Slide
+	source []byte
+	err    error
+	done   chan struct{}
+}
+
+// splitFiles splits each input into slides in a directory of its own below
+// opts.Writer, mirroring where it was found, and fails before splitting any
+// when two would share one. Up to jobs inputs are split at once, one per CPU
+// when jobs is 0, but the slides are written in input order. A manifest and
+// index covering every input are written at the top when opts asks for them.
+// Inputs that fail are reported together, after the others are written.
+func splitFiles(inputs []input, opts SplitOptions, jobs int) error {
+	dirs := map[string]string{}
+	for _, in := range inputs {
+		if other, ok := dirs[in.dir()]; ok {
+			return fmt.Errorf("%s and %s would share the output directory %s", other, in.path, in.dir())
+		}
+		dirs[in.dir()] = in.path
+	}
+
+	if jobs <= 0 {
+		jobs = runtime.NumCPU()
+	}
+	results := make([]splitResult, len(inputs))
+	for i := range results {
+		results[i].done = make(chan struct{})
+	}
+	// Returning early stops the feeding of files to the workers and waits
+	// for those already being split.
+	work, stop := make(chan int), make(chan struct{})
+	var wg sync.WaitGroup
+	defer func() {
+		close(stop)
+		wg.Wait()
+	}()
+	for range min(jobs, len(inputs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				r := &results[i]
+				fileOpts := opts
+				fileOpts.SourceName = inputs[i].rel
+				r.source, r.err = os.ReadFile(inputs[i].path)
+				if r.err == nil {
+					r.slides, r.err = SplitSlides(r.source, fileOpts)
+				}
+				close(r.done)
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(work)
+		for i := range inputs {
+			select {
+			case work <- i:
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	combined := Manifest{Slides: []ManifestSlide{}}
+	var errs []error
+	for i, in := range inputs {
+		r := &results[i]
+		<-r.done
+		if r.err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", in.path, r.err))
+			continue
+		}
+		for _, slide := range r.slides {
+			if err := opts.Writer.WriteFile(path.Join(in.dir(), slide.Name), slide.Content); err != nil {
+				return err
+			}
+		}
+		for _, slide := range NewManifest(r.slides, r.source, in.rel).Slides {
+			slide.File = path.Join(in.dir(), slide.File)
+			slide.Source = in.rel
+			combined.Slides = append(combined.Slides, slide)
+		}
+		r.slides, r.source = nil, nil
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	if opts.Manifest {
+		out, err := combined.JSON()
+		if err != nil {
+			return err
+		}
+		if err := opts.Writer.WriteFile(ManifestName, out); err != nil {
+			return err
+		}
+	}
+	if opts.Index {
+		if err := opts.Writer.WriteFile(IndexName, combined.Index()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+package mdsplit
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMatchParts(t *testing.T) {
+	testCases := []struct {
+		pattern, name string
+		expected      bool
+	}{
+		{"*.md", "a.md", true},
+		{"*.md", "guide/a.md", false},
+		{"**/*.md", "a.md", true},
+		{"**/*.md", "guide/deep/a.md", true},
+		{"guide/**/a.md", "guide/a.md", true},
+		{"guide/**/a.md", "other/a.md", false},
+	}
+	for _, tc := range testCases {
+		if got := matchParts(strings.Split(tc.pattern, "/"), strings.Split(tc.name, "/")); got != tc.expected {
+			t.Errorf("matchParts(%q, %q) = %v, expected %v", tc.pattern, tc.name, got, tc.expected)
+		}
+	}
+}
+
+// writeTree creates the files in a temporary directory and returns it.
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestExpandInputs(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"docs/intro.md":             "# Intro\n",
+		"docs/guide/setup.md":       "# Setup\n",
+		"docs/guide/notes.txt":      "not Markdown",
+		"docs/.git/HEAD.md":         "hidden",
+		"docs/slides/slide-1.md":    "# Output\n",
+		"docs/guide/usage.markdown": "# Usage\n",
+	})
+	docs := filepath.Join(root, "docs")
+
+	testCases := []struct {
+		name     string
+		patterns []string
+		expected []string // Relative paths of the inputs
+		multi    bool
+	}{
+		{name: "file", patterns: []string{filepath.Join(docs, "intro.md")}, expected: []string{"intro.md"}},
+		{name: "directory", patterns: []string{docs}, expected: []string{"guide/setup.md", "guide/usage.markdown", "intro.md"}, multi: true},
+		{name: "glob", patterns: []string{filepath.Join(docs, "*", "*.md")}, expected: []string{"guide/setup.md"}, multi: true},
+		{name: "recursive glob", patterns: []string{filepath.Join(docs, "**", "s*.md")}, expected: []string{"guide/setup.md"}, multi: true},
+		{name: "files", patterns: []string{filepath.Join(docs, "intro.md"), filepath.Join(docs, "guide", "setup.md")}, expected: []string{"intro.md", "setup.md"}, multi: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			inputs, multi, err := expandInputs(tc.patterns, filepath.Join(docs, "slides"))
+			if err != nil {
+				t.Fatalf("expandInputs failed: %v", err)
+			}
+			var rels []string
+			for _, in := range inputs {
+				rels = append(rels, in.rel)
+			}
+			if !reflect.DeepEqual(rels, tc.expected) || multi != tc.multi {
+				t.Errorf("expandInputs = %q %v, expected %q %v", rels, multi, tc.expected, tc.multi)
+			}
+		})
+	}
+
+	inputs, _, err := expandInputs([]string{docs, filepath.Join(docs, "intro.md")}, filepath.Join(docs, "slides"))
+	if err != nil || len(inputs) != 3 {
+		t.Errorf("naming a file twice gave %d inputs and %v, expected 3", len(inputs), err)
+	}
+}
+
+func TestExpandInputsDefaultOut(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"docs/intro.md":       "# Intro\n",
+		"docs/guide/setup.md": "# Setup\n",
+		"empty/notes.txt":     "not Markdown",
+	})
+	t.Chdir(root)
+
+	expected := []string{"guide/setup.md", "intro.md"}
+	for _, pattern := range []string{"docs", "docs/**/*.md"} {
+		inputs, _, err := expandInputs([]string{pattern}, ".")
+		if err != nil {
+			t.Fatalf("expandInputs(%q) failed: %v", pattern, err)
+		}
+		var rels []string
+		for _, in := range inputs {
+			rels = append(rels, in.rel)
+		}
+		if !reflect.DeepEqual(rels, expected) {
+			t.Errorf("expandInputs(%q) = %q, expected %q", pattern, rels, expected)
+		}
+	}
+
+	if _, _, err := expandInputs([]string{"empty"}, "."); err == nil || !strings.Contains(err.Error(), "no Markdown files") {
+		t.Errorf("expected an error for a directory without Markdown files, got %v", err)
+	}
+}
+
+func TestSplitFiles(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"intro.md":       "# Intro\n\nHello.\n",
+		"guide/setup.md": "# Setup\n\nOne.\n\n# More\n\nTwo.\n",
+	})
+	inputs, _, err := expandInputs([]string{root}, "")
+	if err != nil {
+		t.Fatalf("expandInputs failed: %v", err)
+	}
+	w := NewMemWriter()
+	if err := splitFiles(inputs, SplitOptions{MaxHeight: 4, Writer: w, Manifest: true}, 2); err != nil {
+		t.Fatalf("splitFiles failed: %v", err)
+	}
+
+	var names []string
+	fs.WalkDir(w.FS(), ".", func(p string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			names = append(names, p)
+		}
+		return err
+	})
+	expected := []string{"guide/setup/slide-1.md", "guide/setup/slide-2.md", "intro/slide-1.md", "manifest.json"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("files = %q, expected %q", names, expected)
+	}
+
+	manifest, err := fs.ReadFile(w.FS(), "manifest.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"file": "guide/setup/slide-2.md"`, `"source": "guide/setup.md"`, `"file": "intro/slide-1.md"`} {
+		if !strings.Contains(string(manifest), want) {
+			t.Errorf("manifest lacks %s:\n%s", want, manifest)
+		}
+	}
+}
+
+func TestSplitFilesSharedDirectory(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"a/intro.md": "# A\n",
+		"b/intro.md": "# B\n",
+	})
+	inputs, _, err := expandInputs([]string{filepath.Join(root, "a", "intro.md"), filepath.Join(root, "b", "intro.md")}, "")
+	if err != nil {
+		t.Fatalf("expandInputs failed: %v", err)
+	}
+	w := NewMemWriter()
+	err = splitFiles(inputs, SplitOptions{Writer: w}, 1)
+	if err == nil || !strings.Contains(err.Error(), "share the output directory") {
+		t.Errorf("expected an error for two files sharing a directory, got %v", err)
+	}
+	if names, _ := fs.Glob(w.FS(), "*/*"); len(names) > 0 {
+		t.Errorf("expected nothing written, got %q", names)
+	}
+}
+
+// failingWriter fails every write.
+type failingWriter struct{}
+
+func (failingWriter) WriteFile(string, []byte) error { return errors.New("disk full") }
+func (failingWriter) Close() error                   { return nil }
+
+// slowMeasurer counts the text it measures, taking its time over each.
+type slowMeasurer struct{ calls *atomic.Int64 }
+
+func (m slowMeasurer) TextWidth(s string, size float64, mono bool) float64 {
+	m.calls.Add(1)
+	time.Sleep(time.Millisecond)
+	return AverageWidthMeasurer{}.TextWidth(s, size, mono)
+}
+
+func TestSplitFilesWriteError(t *testing.T) {
+	tree := map[string]string{}
+	for i := range 20 {
+		tree[fmt.Sprintf("doc%d.md", i)] = "# Doc\n\nSome text.\n"
+	}
+	inputs, _, err := expandInputs([]string{writeTree(t, tree)}, "")
+	if err != nil {
+		t.Fatalf("expandInputs failed: %v", err)
+	}
+	m := slowMeasurer{calls: new(atomic.Int64)}
+	if err := splitFiles(inputs, SplitOptions{Writer: failingWriter{}, MaxWidth: 600, Measurer: m}, 2); err == nil {
+		t.Fatal("expected the write error")
+	}
+	calls := m.calls.Load()
+	time.Sleep(50 * time.Millisecond)
+	if m.calls.Load() != calls {
+		t.Error("files were still being split after splitFiles returned")
+	}
+}
@@ -0,0 +1,231 @@
+package mdsplit
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+)
+
+// Document is one of the Markdown files SplitDocuments joins into one deck.
+type Document struct {
+	Name string // Path of the file, which its relative links and images are resolved from
+	Data []byte
+}
+
+// joinedDocument places a Document in the source joined from several.
+type joinedDocument struct {
+	Document
+	start    int    // Offset of the document in the joined source, or of its title when it has one
+	body     int    // Offset of the document's own text
+	linkBase string // Slash-separated path from the output directory to the document's, or empty when they are the same
+}
+
+// SplitDocuments joins docs, in order, into one document and splits it as
+// Split does, numbering the slides in a single sequence. Relative links and
+// images are rewritten to resolve from opts.OutDir. With opts.DocumentBreaks
+// each document starts a new slide, and with opts.TitleSlides it is opened by
+// a slide of its title, taken from the title in its front matter or else its
+// file name. The manifest and index record the document each slide came from.
+func SplitDocuments(docs []Document, opts SplitOptions) error {
+	slides, err := SplitDocumentSlides(docs, opts)
+	if err != nil {
+		return err
+	}
+	m := Manifest{Source: opts.SourceName, Slides: []ManifestSlide{}}
+	sources := documentSources(docs)
+	for _, slide := range slides {
+		ms := manifestSlide(slide, sources[slide.Source])
+		ms.Source = slide.Source
+		m.Slides = append(m.Slides, ms)
+	}
+	return writeSlides(slides, m, opts)
+}
+
+// SplitDocumentSlides joins docs as SplitDocuments does and splits them into
+// slides held in memory. Each slide's Source names the document it begins
+// in, and its Start and End are offsets into that document. Only the options
+// in the front matter of the first document apply, as the deck is split in
+// one go.
+func SplitDocumentSlides(docs []Document, opts SplitOptions) ([]Slide, error) {
+	data, joined, err := joinDocuments(docs, &opts)
+	if err != nil {
+		return nil, err
+	}
+	slides, err := splitSource(data, joined, opts)
+	if err != nil {
+		return nil, err
+	}
+	locateSlides(slides, joined)
+	if err := nameSlides(slides, opts.NameTemplate, opts.SourceName); err != nil {
+		return nil, err
+	}
+	if opts.SlideFrontMatter {
+		if err := addSlideFrontMatter(slides, documentSources(docs), opts); err != nil {
+			return nil, err
+		}
+	}
+	return slides, nil
+}
+
+// joinDocuments returns the source joined from docs, with their front matter
+// blanked out and their titles added when opts asks for title slides, along
+// with where each document lies in it. The first document's front matter
+// options are applied to opts.
+func joinDocuments(docs []Document, opts *SplitOptions) ([]byte, []joinedDocument, error) {
+	out, err := filepath.Abs(cmp.Or(opts.OutDir, "."))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var data []byte
+	joined := make([]joinedDocument, len(docs))
+	for i, doc := range docs {
+		text := doc.Data
+		title := ""
+		if fm, ok := findFrontMatter(text); ok {
+			if i == 0 {
+				m, err := fm.options()
+				if err == nil {
+					err = applyDocumentOptions(opts, m)
+				}
+				if err != nil {
+					return nil, nil, fmt.Errorf("%s: %w", doc.Name, err)
+				}
+			}
+			title = fm.title()
+			text = fm.blank(text)
+		}
+
+		j := &joined[i]
+		j.Document = doc
+		j.start = len(data)
+		if opts.TitleSlides {
+			if title == "" && doc.Name != "" {
+				title = strings.TrimSuffix(filepath.Base(doc.Name), filepath.Ext(doc.Name))
+			}
+			if title == "" {
+				title = fmt.Sprintf("Part %d", i+1)
+			}
+			data = append(data, "# "+escapeMarkdown(title)+"\n\n"...)
+		}
+		j.body = len(data)
+		data = append(data, text...)
+		// A blank line keeps the last block from running into the next document.
+		if !bytes.HasSuffix(data, []byte("\n")) {
+			data = append(data, '\n')
+		}
+		data = append(data, '\n')
+
+		dir, err := filepath.Abs(filepath.Dir(doc.Name))
+		if err != nil {
+			return nil, nil, err
+		}
+		rel, err := filepath.Rel(out, dir)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", doc.Name, err)
+		}
+		if rel != "." {
+			parts := strings.Split(filepath.ToSlash(rel), "/")
+			for k, part := range parts {
+				parts[k] = url.PathEscape(part)
+			}
+			j.linkBase = strings.Join(parts, "/")
+		}
+	}
+	return data, joined, nil
+}
+
+// locateSlides points each slide split from joined documents at the document
+// it begins in, with its range made an offset into that document's text.
+// Title slides have no range in the document.
+func locateSlides(slides []Slide, docs []joinedDocument) {
+	source := ""
+	for i := range slides {
+		slide := &slides[i]
+		if slide.Start < 0 {
+			slide.Source = source
+			continue
+		}
+		k := 0
+		for k+1 < len(docs) && docs[k+1].start <= slide.Start {
+			k++
+		}
+		doc := docs[k]
+		slide.Source, source = doc.Name, doc.Name
+		if slide.End <= doc.body {
+			slide.Start, slide.End = -1, -1
+			continue
+		}
+		slide.Start = max(slide.Start, doc.body) - doc.body
+		slide.End = min(slide.End, doc.body+len(doc.Data)) - doc.body
+	}
+}
+
+// documentSources maps the names of docs to their text.
+func documentSources(docs []Document) map[string][]byte {
+	sources := make(map[string][]byte, len(docs))
+	for _, doc := range docs {
+		sources[doc.Name] = doc.Data
+	}
+	return sources
+}
+
+// rewriteLinks prefixes the relative destinations of the links and images
+// under n with base, so that they resolve from the output directory.
+func rewriteLinks(n ast.Node, base string) {
+	if base == "" {
+		return
+	}
+	_ = ast.Walk(n, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.Link:
+			n.Destination = rebaseLink(n.Destination, base)
+		case *ast.Image:
+			n.Destination = rebaseLink(n.Destination, base)
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+// rebaseLink returns dest joined to base when it is a relative path, and
+// unchanged when it is a URL, an absolute path or a fragment of the page.
+func rebaseLink(dest []byte, base string) []byte {
+	p, ok := relativeLink(string(dest))
+	if !ok {
+		return dest
+	}
+	joined := path.Join(base, p)
+	if strings.HasSuffix(p, "/") {
+		joined += "/"
+	}
+	return []byte(joined + string(dest[len(p):]))
+}
+
+// relativeLink returns the path of dest, without any query or fragment, if
+// it is relative to the document linking to it.
+func relativeLink(dest string) (string, bool) {
+	if dest == "" || strings.HasPrefix(dest, "#") || strings.HasPrefix(dest, "/") {
+		return "", false
+	}
+	if u, err := url.Parse(dest); err != nil || u.Scheme != "" {
+		return "", false
+	}
+	if i := strings.IndexAny(dest, "?#"); i >= 0 {
+		dest = dest[:i]
+	}
+	return dest, true
+}
+
+// escapeMarkdown escapes the characters that would read as inline markup.
+func escapeMarkdown(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "[", `\[`, "]", `\]`, "<", `\<`).Replace(s)
+}
@@ -0,0 +1,126 @@
+package mdsplit
+
+import (
+	"io/fs"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRebaseLink(t *testing.T) {
+	testCases := []struct {
+		dest, expected string
+	}{
+		{"img/a.png", "../docs/img/a.png"},
+		{"./a.md#part", "../docs/a.md#part"},
+		{"../other/", "../other/"},
+		{"#local", "#local"},
+		{"/abs/a.png", "/abs/a.png"},
+		{"https://example.com/a.png", "https://example.com/a.png"},
+		{"mailto:someone@example.com", "mailto:someone@example.com"},
+		{"", ""},
+	}
+	for _, tc := range testCases {
+		if got := string(rebaseLink([]byte(tc.dest), "../docs")); got != tc.expected {
+			t.Errorf("rebaseLink(%q) = %q, expected %q", tc.dest, got, tc.expected)
+		}
+	}
+}
+
+func TestSplitDocumentSlides(t *testing.T) {
+	root := t.TempDir()
+	docs := []Document{
+		{Name: filepath.Join(root, "book", "one.md"), Data: []byte("---\ntitle: The Start\n---\n# One\n\n![pic](img/a.png)\n")},
+		{Name: filepath.Join(root, "book", "two.md"), Data: []byte("More of [one](one.md) and [the web](https://example.com).\n")},
+	}
+	out := filepath.Join(root, "slides")
+
+	testCases := []struct {
+		name     string
+		opts     SplitOptions
+		expected []string
+		reasons  []BreakReason
+		sources  []string
+	}{
+		{
+			name:     "continuous",
+			opts:     SplitOptions{OutDir: out},
+			expected: []string{"# One\n\n![pic](../book/img/a.png)\n\nMore of [one](../book/one.md) and [the web](https://example.com).\n\n"},
+			reasons:  []BreakReason{BreakStart},
+			sources:  []string{"one.md"},
+		},
+		{
+			name: "document breaks",
+			opts: SplitOptions{OutDir: out, DocumentBreaks: true},
+			expected: []string{
+				"# One\n\n![pic](../book/img/a.png)\n\n",
+				"More of [one](../book/one.md) and [the web](https://example.com).\n\n",
+			},
+			reasons: []BreakReason{BreakStart, BreakDocument},
+			sources: []string{"one.md", "two.md"},
+		},
+		{
+			name: "title slides",
+			opts: SplitOptions{OutDir: out, TitleSlides: true},
+			expected: []string{
+				"# The Start\n\n",
+				"# One\n\n![pic](../book/img/a.png)\n\n",
+				"# two\n\n",
+				"More of [one](../book/one.md) and [the web](https://example.com).\n\n",
+			},
+			reasons: []BreakReason{BreakStart, BreakDocument, BreakDocument, BreakDocument},
+			sources: []string{"one.md", "one.md", "two.md", "two.md"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			slides, err := SplitDocumentSlides(docs, tc.opts)
+			if err != nil {
+				t.Fatalf("SplitDocumentSlides failed: %v", err)
+			}
+			var contents, sources []string
+			var reasons []BreakReason
+			for _, slide := range slides {
+				contents = append(contents, string(slide.Content))
+				reasons = append(reasons, slide.Reason)
+				sources = append(sources, filepath.Base(slide.Source))
+			}
+			if !reflect.DeepEqual(contents, tc.expected) {
+				t.Errorf("contents = %q, expected %q", contents, tc.expected)
+			}
+			if !reflect.DeepEqual(reasons, tc.reasons) {
+				t.Errorf("reasons = %q, expected %q", reasons, tc.reasons)
+			}
+			if !reflect.DeepEqual(sources, tc.sources) {
+				t.Errorf("sources = %q, expected %q", sources, tc.sources)
+			}
+		})
+	}
+}
+
+func TestSplitDocuments(t *testing.T) {
+	docs := []Document{
+		{Name: "one.md", Data: []byte("---\nmdsplit:\n  max-height: 4\n---\n# One\n\nFirst.\n")},
+		{Name: "two.md", Data: []byte("# Two\n\nSecond.\n\nThird.\n")},
+	}
+	w := NewMemWriter()
+	if err := SplitDocuments(docs, SplitOptions{Writer: w, Manifest: true, Index: true}); err != nil {
+		t.Fatalf("SplitDocuments failed: %v", err)
+	}
+
+	names, _ := fs.Glob(w.FS(), "slide-*.md")
+	if len(names) != 3 {
+		t.Errorf("expected 3 slides with the first document's max-height, got %q", names)
+	}
+	manifest, _ := fs.ReadFile(w.FS(), ManifestName)
+	for _, want := range []string{`"source": "two.md"`, `"first_line": 5`, `"first_line": 1`} {
+		if !strings.Contains(string(manifest), want) {
+			t.Errorf("manifest lacks %s:\n%s", want, manifest)
+		}
+	}
+	index, _ := fs.ReadFile(w.FS(), IndexName)
+	if !strings.Contains(string(index), "## one.md") || !strings.Contains(string(index), "## two.md") {
+		t.Errorf("index is not grouped by document:\n%s", index)
+	}
+}
@@ -0,0 +1,299 @@
+package mdsplit
+
+import (
+	"math"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/yuin/goldmark/ast"
+	extast "github.com/yuin/goldmark/extension/ast"
+	"golang.org/x/text/width"
+)
+
+// Measurer measures how wide text is when rendered.
+type Measurer interface {
+	// TextWidth returns the width in pixels of s set at size pixels, in a
+	// monospaced face when mono is true.
+	TextWidth(s string, size float64, mono bool) float64
+}
+
+// AverageWidthMeasurer estimates text width from average glyph advances:
+// half an em for proportional text, 0.6em for monospaced text and a full em
+// for East Asian wide characters such as CJK and most emoji.
+type AverageWidthMeasurer struct{}
+
+func (AverageWidthMeasurer) TextWidth(s string, size float64, mono bool) float64 {
+	narrow := 0.5
+	if mono {
+		narrow = 0.6
+	}
+	var ems float64
+	for _, r := range s {
+		switch {
+		case r == '\t':
+			ems += 4 * narrow
+		case unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Me, r) || r == '\u200d' || unicode.Is(unicode.Variation_Selector, r):
+			// Combining marks and joiners take no space of their own.
+		case isWide(r):
+			ems += 1
+		default:
+			ems += narrow
+		}
+	}
+	return ems * size
+}
+
+func isWide(r rune) bool {
+	switch width.LookupRune(r).Kind() {
+	case width.EastAsianWide, width.EastAsianFullwidth:
+		return true
+	}
+	return false
+}
+
+// headingScale is the font size of each heading level relative to body text.
+var headingScale = [...]float64{1, 2, 1.5, 1.25, 1, 0.875, 0.85}
+
+// linkTarget matches the destination part of inline links and images, which
+// is not displayed.
+var linkTarget = regexp.MustCompile(`\]\([^)]*\)`)
+
+// layout estimates how many visual lines rendered Markdown takes up on a
+// slide of a given pixel width.
+type layout struct {
+	width    float64 // Usable width in pixels, or 0 to count source lines only
+	fontSize float64 // Body font size in pixels
+	measurer Measurer
+}
+
+func newLayout(opts SplitOptions) layout {
+	l := layout{
+		width:    float64(opts.MaxWidth),
+		fontSize: float64(opts.FontSize) * float64(opts.DPI) / 72,
+		measurer: opts.Measurer,
+	}
+	if l.measurer == nil {
+		l.measurer = AverageWidthMeasurer{}
+	}
+	if l.fontSize <= 0 {
+		l.width = 0
+	}
+	return l
+}
+
+// style returns the font scale and whether text of kind is monospaced.
+func (l layout) style(n ast.Node) (scale float64, mono bool) {
+	switch n.Kind() {
+	case ast.KindHeading:
+		level := n.(*ast.Heading).Level
+		if level >= 0 && level < len(headingScale) {
+			return headingScale[level], false
+		}
+	case ast.KindFencedCodeBlock, ast.KindCodeBlock:
+		return 1, true
+	}
+	return 1, false
+}
+
+// lineCount returns the number of visual lines the rendered content of n
+// takes up. Only lines terminated by a newline are counted, matching a plain
+// count of newlines when wrapping is disabled.
+func (l layout) lineCount(content string, n ast.Node) int {
+	lines := strings.Split(content, "\n")
+	lines = lines[:len(lines)-1]
+	if l.width <= 0 || n.Kind() == extast.KindTable {
+		return len(lines)
+	}
+	if n.Kind() == ast.KindParagraph {
+		return l.paragraphHeight(lines)
+	}
+	scale, mono := l.style(n)
+	count := 0
+	for _, line := range lines {
+		count += l.lineHeight(line, scale, mono)
+	}
+	return count
+}
+
+// lineHeight returns the number of visual lines a single source line wraps to.
+func (l layout) lineHeight(line string, scale float64, mono bool) int {
+	if l.width <= 0 {
+		return 1
+	}
+	if mono {
+		w := l.measurer.TextWidth(line, l.fontSize*scale, true)
+		return max(1, int(math.Ceil(w/l.width)))
+	}
+	indent := line[:len(line)-len(strings.TrimLeft(line, " "))]
+	w := l.newWrapper(scale, indent)
+	for _, word := range strings.Fields(line) {
+		w.add(l.wordWidth(word, scale))
+	}
+	return max(1, w.total())
+}
+
+// wrap breaks line at spaces so that each piece fits the slide width. Words
+// wider than the slide are left on a line of their own.
+func (l layout) wrap(line string, scale float64) []string {
+	if l.width <= 0 {
+		return []string{line}
+	}
+	indent := line[:len(line)-len(strings.TrimLeft(line, " "))]
+	words := strings.Fields(line)
+	if len(words) == 0 {
+		return []string{line}
+	}
+	w := l.newWrapper(scale, indent)
+	var wrapped []string
+	first := 0 // First word of the current piece
+	for i, word := range words {
+		if w.add(l.wordWidth(word, scale)) {
+			wrapped = append(wrapped, indent+strings.Join(words[first:i], " "))
+			first = i
+		}
+	}
+	return append(wrapped, indent+strings.Join(words[first:], " "))
+}
+
+// wordWidth returns the width of a word as displayed at scale.
+func (l layout) wordWidth(word string, scale float64) float64 {
+	return l.measurer.TextWidth(displayText(word), l.fontSize*scale, false)
+}
+
+// wrapper lays words out greedily on lines of the slide width, keeping a
+// running width so that each word is measured only once. A line's width is
+// that of its words and the spaces between them.
+type wrapper struct {
+	width  float64 // Width of the slide
+	space  float64 // Width of the space between words
+	indent float64 // Width of the indentation opening each line
+	height int     // Visual lines taken by the lines already ended
+	line   float64 // Width of the current line, or -1 before its first word
+}
+
+func (l layout) newWrapper(scale float64, indent string) wrapper {
+	size := l.fontSize * scale
+	return wrapper{
+		width:  l.width,
+		space:  l.measurer.TextWidth(" ", size, false),
+		indent: l.measurer.TextWidth(indent, size, false),
+		line:   -1,
+	}
+}
+
+// add places a word of the given width, reporting whether it did not fit on
+// the current line and starts a new one.
+func (w *wrapper) add(width float64) bool {
+	if w.line >= 0 && w.line+w.space+width <= w.width {
+		w.line += w.space + width
+		return false
+	}
+	broke := w.line >= 0
+	w.end()
+	w.line = w.indent + width
+	return broke
+}
+
+// end ends the current line. A line wider than the slide, holding a single
+// long word, takes as many visual lines as it needs.
+func (w *wrapper) end() {
+	if w.line >= 0 {
+		w.height += max(1, int(math.Ceil(w.line/w.width)))
+		w.line = -1
+	}
+}
+
+// total returns the visual lines the words added so far take.
+func (w wrapper) total() int {
+	w.end()
+	return w.height
+}
+
+// paragraphHeight returns the number of visual lines the rendered lines of a
+// paragraph take when laid out as wrapParagraph lays them out. The marker of
+// a hard line break takes no room.
+func (l layout) paragraphHeight(lines []string) int {
+	height := 0
+	w := l.newWrapper(1, "")
+	addWords := func(text string) {
+		for _, word := range strings.Fields(text) {
+			w.add(l.wordWidth(word, 1))
+		}
+	}
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			height += w.total() + 1
+			w = l.newWrapper(1, "")
+		case strings.HasSuffix(line, "  "):
+			addWords(trimmed)
+			height += max(1, w.total())
+			w = l.newWrapper(1, "")
+		case strings.HasSuffix(trimmed, "\\"):
+			addWords(strings.TrimSuffix(trimmed, "\\"))
+			height += max(1, w.total())
+			w = l.newWrapper(1, "")
+		default:
+			addWords(trimmed)
+		}
+	}
+	return height + w.total()
+}
+
+// wrapParagraph joins the soft-broken lines of a paragraph, as a renderer
+// would, and wraps the result to the slide width. Hard line breaks are kept.
+func (l layout) wrapParagraph(lines []string) []string {
+	var wrapped []string
+	var flow []string
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			// Blank lines around the paragraph are spacing, not text.
+			if len(flow) > 0 {
+				wrapped = append(wrapped, l.wrapFlow(flow, "")...)
+				flow = nil
+			}
+			wrapped = append(wrapped, line)
+		case strings.HasSuffix(line, "  "):
+			wrapped = append(wrapped, l.wrapFlow(append(flow, trimmed), "  ")...)
+			flow = nil
+		case strings.HasSuffix(trimmed, "\\"):
+			wrapped = append(wrapped, l.wrapFlow(append(flow, strings.TrimSuffix(trimmed, "\\")), "\\")...)
+			flow = nil
+		default:
+			flow = append(flow, trimmed)
+		}
+	}
+	if len(flow) > 0 {
+		wrapped = append(wrapped, l.wrapFlow(flow, "")...)
+	}
+	return wrapped
+}
+
+// fallbackColumns is the number of characters source text is wrapped to when
+// the slide width is unknown.
+const fallbackColumns = 60
+
+// wrapText wraps source text to the slide width, or to fallbackColumns
+// characters when no width is set.
+func (l layout) wrapText(content []byte) []byte {
+	if l.width <= 0 {
+		return wrapText(content, fallbackColumns)
+	}
+	return []byte(strings.Join(l.wrapParagraph(trimmedLines(content)), "\n") + "\n")
+}
+
+// wrapFlow wraps lines joined into one, ending the last piece with hardBreak.
+func (l layout) wrapFlow(flow []string, hardBreak string) []string {
+	wrapped := l.wrap(strings.Join(flow, " "), 1)
+	wrapped[len(wrapped)-1] += hardBreak
+	return wrapped
+}
+
+// displayText strips Markdown syntax that takes no room when rendered.
+func displayText(s string) string {
+	return linkTarget.ReplaceAllString(s, "]")
+}
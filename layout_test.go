@@ -0,0 +1,62 @@
+package mdsplit
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/yuin/goldmark/ast"
+)
+
+func TestAverageWidthMeasurer(t *testing.T) {
+	m := AverageWidthMeasurer{}
+	testCases := []struct {
+		name     string
+		text     string
+		mono     bool
+		expected float64
+	}{
+		{name: "latin", text: "abcd", expected: 32},
+		{name: "monospaced", text: "abcd", mono: true, expected: 38.4},
+		{name: "cjk", text: "日本語", expected: 48},
+		{name: "combining mark", text: "é", expected: 8},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := m.TextWidth(tc.text, 16, tc.mono); got != tc.expected {
+				t.Errorf("TextWidth(%q) = %v, expected %v", tc.text, got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestLayoutLineCount(t *testing.T) {
+	// 12pt at 96 DPI is 16px, so a 600px slide fits 75 average characters.
+	lay := newLayout(SplitOptions{MaxWidth: 600, FontSize: 12, DPI: 96})
+	paragraph := ast.NewParagraph()
+	code := ast.NewFencedCodeBlock(nil)
+
+	testCases := []struct {
+		name     string
+		content  string
+		node     ast.Node
+		expected int
+	}{
+		{name: "short paragraph", content: "Short.\n\n", node: paragraph, expected: 2},
+		{name: "wrapped paragraph", content: strings.Repeat("word ", 40) + "\n\n", node: paragraph, expected: 4},
+		{name: "soft breaks reflow", content: strings.Repeat("tiny\n", 10) + "\n", node: paragraph, expected: 2},
+		{name: "hard breaks kept", content: "one  \ntwo\n\n", node: paragraph, expected: 3},
+		{name: "long code line", content: "```\n" + strings.Repeat("x", 100) + "\n```\n\n", node: code, expected: 5},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := lay.lineCount(tc.content, tc.node); got != tc.expected {
+				t.Errorf("lineCount(%q) = %d, expected %d", tc.content, got, tc.expected)
+			}
+		})
+	}
+
+	unwrapped := newLayout(SplitOptions{FontSize: 12, DPI: 96})
+	if got := unwrapped.lineCount(strings.Repeat("word ", 40)+"\n\n", paragraph); got != 2 {
+		t.Errorf("lineCount without MaxWidth = %d, expected 2", got)
+	}
+}
@@ -0,0 +1,219 @@
+package mdsplit
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+)
+
+// listMarker matches the marker at the start of a list item line once its
+// indentation has been removed.
+var listMarker = regexp.MustCompile(`^(\d{1,9}[.)]|[-*+])( +|$)`)
+
+// listUnit is a run of rendered list lines that is never split further.
+type listUnit struct {
+	lines       []string
+	height      int
+	start, stop int // Source range of the unit, or -1 when it is unknown
+}
+
+// splitList splits the rendered list into parts of at most maxHeight visual
+// lines, breaking only between list items. Items too tall for a slide are
+// broken between their nested items, repeating the item's own text on each
+// part. Ordered lists keep their numbering because every item keeps its
+// number, which a continuation slide's list then starts from. The first part
+// shares its slide with lead lines of earlier content, and note lines are
+// left for a continuation note. Each part is attributed to the items it
+// holds.
+func splitList(b block, source []byte, maxHeight, lead, note int, lay layout) []slidePart {
+	budget := maxHeight - noteSpace(note) // Account for the continuation note.
+	if budget <= 0 {
+		budget = 1
+	}
+	units := listUnits(trimmedLines(b.content), 0, budget, b.node, source, lay)
+
+	var parts []slidePart
+	for first := 0; first < len(units); {
+		room := budget
+		if first == 0 {
+			room -= lead
+		}
+		used, count := 0, 0
+		for _, u := range units[first:] {
+			if count > 0 && used+u.height > room {
+				break
+			}
+			used += u.height
+			count++
+		}
+
+		var slideContent bytes.Buffer
+		start, stop := -1, -1
+		for _, u := range units[first : first+count] {
+			slideContent.WriteString(strings.Join(u.lines, "\n"))
+			slideContent.WriteString("\n")
+			start, stop = widenRange(start, stop, u.start, u.stop)
+		}
+		if start < 0 {
+			start, stop = b.start, b.stop
+		}
+		parts = append(parts, slidePart{content: slideContent.Bytes(), start: start, stop: stop})
+		first += count
+	}
+	return parts
+}
+
+// listUnits breaks list lines into items at the given indentation, breaking
+// any item taller than budget into smaller units. Items map back to the
+// source through the items of list, when there are as many of them.
+func listUnits(lines []string, indent, budget int, list ast.Node, source []byte, lay layout) []listUnit {
+	var units []listUnit
+	items := listItems(lines, indent)
+	nodes := listItemNodes(list, len(items))
+	for i, item := range items {
+		var node ast.Node
+		start, stop := -1, -1
+		if nodes != nil {
+			node = nodes[i]
+			start, stop = sourceRange(node, source)
+		}
+		height := listHeight(item, lay)
+		if height <= budget {
+			units = append(units, listUnit{lines: item, height: height, start: start, stop: stop})
+			continue
+		}
+		units = append(units, splitListItem(item, indent, budget, node, source, lay)...)
+	}
+	return units
+}
+
+// listItemNodes returns the items of list, or nil when list is nil or does
+// not have count items.
+func listItemNodes(list ast.Node, count int) []ast.Node {
+	if list == nil {
+		return nil
+	}
+	var nodes []ast.Node
+	for item := list.FirstChild(); item != nil; item = item.NextSibling() {
+		if item.Kind() == ast.KindListItem {
+			nodes = append(nodes, item)
+		}
+	}
+	if len(nodes) != count {
+		return nil
+	}
+	return nodes
+}
+
+// listItems groups lines into items, each starting at a marker line indented
+// by exactly indent spaces. Lines before the first marker form an item of
+// their own.
+func listItems(lines []string, indent int) [][]string {
+	var items [][]string
+	for _, line := range lines {
+		if len(items) == 0 || isItemStart(line, indent) {
+			items = append(items, nil)
+		}
+		items[len(items)-1] = append(items[len(items)-1], line)
+	}
+	return items
+}
+
+func isItemStart(line string, indent int) bool {
+	trimmed := strings.TrimLeft(line, " ")
+	return len(line)-len(trimmed) == indent && listMarker.MatchString(trimmed)
+}
+
+// splitListItem breaks an item taller than budget between its nested items,
+// or between lines when it has none. Every unit after the first repeats the
+// item's own text so the nesting stays readable, or, when broken between
+// lines, starts with the item's marker so it still reads as a list item.
+// Units are attributed to the nested items they hold, or to the whole item
+// when broken between lines.
+func splitListItem(item []string, indent, budget int, node ast.Node, source []byte, lay layout) []listUnit {
+	start, stop := -1, -1
+	var nested ast.Node
+	if node != nil {
+		start, stop = sourceRange(node, source)
+		for c := node.FirstChild(); c != nil; c = c.NextSibling() {
+			if c.Kind() == ast.KindList {
+				nested = c
+				break
+			}
+		}
+	}
+
+	head := item[:1]
+	childIndent := indent
+	marker := listMarker.FindString(strings.TrimLeft(item[0], " "))
+	if marker != "" {
+		childIndent = indent + len(marker)
+	}
+	rest := item[1:]
+	for i, line := range rest {
+		if isItemStart(line, childIndent) {
+			head = item[:i+1]
+			rest = rest[i:]
+			break
+		}
+	}
+	headHeight := listHeight(head, lay)
+
+	var children []listUnit
+	byLine := false
+	if len(rest) > 0 && isItemStart(rest[0], childIndent) && budget > headHeight {
+		children = listUnits(rest, childIndent, budget-headHeight, nested, source, lay)
+	} else {
+		byLine = true
+		// No nested list to break at, so fall back to single lines.
+		head, rest = nil, item
+		headHeight = 0
+		for _, line := range rest {
+			children = append(children, listUnit{lines: []string{line}, height: listHeight([]string{line}, lay), start: start, stop: stop})
+		}
+	}
+
+	var units []listUnit
+	// The first unit holds the item's own text, so it starts with the item.
+	current := listUnit{lines: append([]string(nil), head...), height: headHeight, start: start, stop: start}
+	hasChild := false
+	for _, child := range children {
+		if hasChild && current.height+child.height > budget {
+			units = append(units, current)
+			current = listUnit{lines: append([]string(nil), head...), height: headHeight, start: -1, stop: -1}
+			hasChild = false
+			if byLine && marker != "" {
+				child = continueItem(child.lines[0], indent, marker, lay)
+			}
+		}
+		if child.start < 0 {
+			child.start, child.stop = start, stop
+		}
+		current.lines = append(current.lines, child.lines...)
+		current.height += child.height
+		current.start, current.stop = widenRange(current.start, current.stop, child.start, child.stop)
+		hasChild = true
+	}
+	return append(units, current)
+}
+
+// continueItem turns a continuation line of a list item into a line opening
+// the item again with the same marker, so that a part of an item broken
+// between lines does not read as an indented code block. Numbered items keep
+// their number, which the continuation slide's list starts from.
+func continueItem(line string, indent int, marker string, lay layout) listUnit {
+	marker = strings.TrimRight(marker, " ") + " "
+	line = strings.Repeat(" ", indent) + marker + strings.TrimLeft(line, " ")
+	return listUnit{lines: []string{line}, height: listHeight([]string{line}, lay)}
+}
+
+// listHeight returns the visual height of rendered list lines.
+func listHeight(lines []string, lay layout) int {
+	height := 0
+	for _, line := range lines {
+		height += lay.lineHeight(line, 1, false)
+	}
+	return height
+}
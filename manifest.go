@@ -0,0 +1,136 @@
+package mdsplit
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Names of the files Split writes next to the slides when asked.
+const (
+	ManifestName = "manifest.json"
+	IndexName    = "index.md"
+)
+
+// Manifest describes the slides split from a document.
+type Manifest struct {
+	Source string          `json:"source,omitempty"` // Name of the source file
+	Slides []ManifestSlide `json:"slides"`
+}
+
+// ManifestSlide describes one slide in a Manifest.
+type ManifestSlide struct {
+	File         string      `json:"file"`                 // File name the slide is written to
+	ID           string      `json:"id"`                   // Stable ID of the slide, as in Slide.ID
+	Hash         string      `json:"hash"`                 // SHA-256 of the slide file's content in hex, which stays the same while the slide does
+	Source       string      `json:"source,omitempty"`     // Source file, in a manifest covering several
+	Index        int         `json:"index"`                // 1-based position of the slide
+	StartByte    int         `json:"start_byte"`           // Byte offset of the first source byte, or -1 if unknown
+	EndByte      int         `json:"end_byte"`             // Byte offset just past the last source byte, or -1 if unknown
+	FirstLine    int         `json:"first_line,omitempty"` // 1-based first source line, or 0 if unknown
+	LastLine     int         `json:"last_line,omitempty"`  // 1-based last source line, or 0 if unknown
+	Headings     []string    `json:"headings"`             // Trail of headings the slide begins under
+	Kinds        []string    `json:"kinds"`                // Kinds of block on the slide
+	Lines        int         `json:"lines"`                // Estimated height in lines
+	Continuation bool        `json:"continuation"`         // Whether the slide carries on a block split from the one before
+	Reason       BreakReason `json:"reason"`               // Why the break before the slide happened
+}
+
+// NewManifest describes slides split from source, a file called name.
+func NewManifest(slides []Slide, source []byte, name string) Manifest {
+	m := Manifest{Source: name, Slides: []ManifestSlide{}}
+	for _, slide := range slides {
+		m.Slides = append(m.Slides, manifestSlide(slide, source))
+	}
+	return m
+}
+
+// manifestSlide describes a slide split from source.
+func manifestSlide(slide Slide, source []byte) ManifestSlide {
+	first, last := lineRange(source, slide.Start, slide.End)
+	return ManifestSlide{
+		File:         slide.Name,
+		ID:           slide.ID,
+		Hash:         contentHash(slide.Content),
+		Index:        slide.Index,
+		StartByte:    slide.Start,
+		EndByte:      slide.End,
+		FirstLine:    first,
+		LastLine:     last,
+		Headings:     append([]string{}, slide.Headings...),
+		Kinds:        append([]string{}, slide.Kinds...),
+		Lines:        slide.Lines,
+		Continuation: slide.Reason == BreakContinuation,
+		Reason:       slide.Reason,
+	}
+}
+
+// JSON returns the manifest as indented JSON.
+func (m Manifest) JSON() ([]byte, error) {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false) // Headings often hold & and <.
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(m); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// Index returns a Markdown list linking to every slide under the heading it
+// begins with, along with the source lines it came from. A manifest covering
+// several source files gets a list for each.
+func (m Manifest) Index() []byte {
+	var b bytes.Buffer
+	title := "Slides"
+	if m.Source != "" {
+		title = m.Source
+	}
+	fmt.Fprintf(&b, "# %s\n\n", title)
+	for i, slide := range m.Slides {
+		if slide.Source != "" && (i == 0 || slide.Source != m.Slides[i-1].Source) {
+			if i > 0 {
+				b.WriteString("\n")
+			}
+			fmt.Fprintf(&b, "## %s\n\n", slide.Source)
+		}
+		text := fmt.Sprintf("Slide %d", slide.Index)
+		if len(slide.Headings) > 0 {
+			text = slide.Headings[len(slide.Headings)-1]
+		}
+		if slide.Continuation {
+			text += " (continued)"
+		}
+		fmt.Fprintf(&b, "%d. [%s](<%s>)", slide.Index, escapeLinkText(text), slide.File)
+		if slide.FirstLine > 0 {
+			fmt.Fprintf(&b, " — lines %d–%d", slide.FirstLine, slide.LastLine)
+		}
+		b.WriteString("\n")
+	}
+	return b.Bytes()
+}
+
+// contentHash returns the SHA-256 of data in hex.
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// escapeLinkText escapes the characters that would end link text early.
+func escapeLinkText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`).Replace(s)
+}
+
+// lineRange returns the 1-based first and last lines of source covered by
+// the byte range [start, end), or zeros when the range is unknown.
+func lineRange(source []byte, start, end int) (first, last int) {
+	if start < 0 || end <= start || end > len(source) {
+		return 0, 0
+	}
+	first = bytes.Count(source[:start], []byte("\n")) + 1
+	last = bytes.Count(source[:end-1], []byte("\n")) + 1
+	return first, last
+}
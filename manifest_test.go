@@ -0,0 +1,55 @@
+package mdsplit
+
+import (
+	"io/fs"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestManifest(t *testing.T) {
+	input := "# Intro\n\nHello.\n\n## Code\n\n```go\n" + strings.Repeat("x++\n", 6) + "```\n"
+	slides, err := SplitSlides([]byte(input), SplitOptions{MaxHeight: 6})
+	if err != nil {
+		t.Fatalf("SplitSlides failed: %v", err)
+	}
+	m := NewManifest(slides, []byte(input), "deck.md")
+
+	expected := []ManifestSlide{
+		{File: "slide-1.md", ID: "intro-1", Index: 1, StartByte: 0, EndByte: 25, FirstLine: 1, LastLine: 5, Headings: []string{"Intro"}, Kinds: []string{"heading", "paragraph"}, Lines: 6, Reason: BreakStart},
+		{File: "slide-2.md", ID: "code-1", Index: 2, StartByte: 32, EndByte: 48, FirstLine: 8, LastLine: 11, Headings: []string{"Intro", "Code"}, Kinds: []string{"code"}, Lines: 6, Reason: BreakOversized},
+		{File: "slide-3.md", ID: "code-2", Index: 3, StartByte: 48, EndByte: 56, FirstLine: 12, LastLine: 13, Headings: []string{"Intro", "Code"}, Kinds: []string{"code"}, Lines: 4, Continuation: true, Reason: BreakContinuation},
+	}
+	for i := range expected {
+		expected[i].Hash = contentHash(slides[i].Content)
+	}
+	if !reflect.DeepEqual(m.Slides, expected) {
+		t.Errorf("manifest slides:\n%+v\nexpected:\n%+v", m.Slides, expected)
+	}
+
+	index := "# deck.md\n\n" +
+		"1. [Intro](<slide-1.md>) — lines 1–5\n" +
+		"2. [Code](<slide-2.md>) — lines 8–11\n" +
+		"3. [Code (continued)](<slide-3.md>) — lines 12–13\n"
+	if got := string(m.Index()); got != index {
+		t.Errorf("index:\n%s\nexpected:\n%s", got, index)
+	}
+}
+
+func TestContentHash(t *testing.T) {
+	if got, expected := contentHash([]byte("abc")), "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"; got != expected {
+		t.Errorf("contentHash = %s, expected %s", got, expected)
+	}
+}
+
+func TestSplitManifest(t *testing.T) {
+	w := NewMemWriter()
+	if err := Split([]byte("# Hello\n"), SplitOptions{Writer: w, Manifest: true, Index: true}); err != nil {
+		t.Fatalf("Split failed: %v", err)
+	}
+	for _, name := range []string{"slide-1.md", ManifestName, IndexName} {
+		if _, err := fs.Stat(w.FS(), name); err != nil {
+			t.Errorf("%s was not written", name)
+		}
+	}
+}
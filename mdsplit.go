@@ -2,9 +2,6 @@ package mdsplit
 
 import (
 	"bytes"
-	"fmt"
-	"os"
-	"path/filepath"
 	"strings"
 
 	markdown "github.com/teekennedy/goldmark-markdown"
@@ -38,13 +35,132 @@ type SplitOptions struct {
 	TemplateSize TemplateSize // Predefined template size (overrides MaxHeight/MaxWidth if set)
 	FontSize     int          // Font size in points (default: 12)
 	DPI          int          // DPI for rendering (default: 96)
+	Writer       SlideWriter  // Destination for Split (default: a DirWriter for OutDir)
+	FontFile     string       // TrueType/OpenType font to measure text with (default: average glyph widths)
+	MonoFontFile string       // Font to measure code with (default: FontFile)
+	Measurer     Measurer     // Text measurement backend (overrides FontFile)
+
+	HeadingBreakLevel   int  // Start a new slide at headings of this level or above (default: 0, disabled)
+	KeepHeadingWithNext bool // Move a heading left at the bottom of a slide onto the next one
+	SplitBySection      bool // One slide per section at HeadingBreakLevel (any heading when 0), ignoring MaxHeight
+
+	BreakOn BreakMarker // Markers the author can write to force a slide break (default: none)
+
+	ListContinuationNote bool              // Note "List continued (part N)" under each part of a split list
+	ContinuationNotes    []string          // Further kinds of split block to note on every part, such as NoteCode
+	NoteTemplates        map[string]string // text/template notes by kind, run with NoteData, replacing the bundled ones ("" for none)
+	NoteLanguage         string            // Language of the bundled notes, one of NoteLanguages (default: en)
+	HeadingBreadcrumb    bool              // Repeat the trail of headings atop every slide that continues a section
+
+	MinLinesBeforeBreak int // Fewest lines of a split table, code block or paragraph left before a break (default: 0, any)
+	MinLinesAfterBreak  int // Fewest lines of a split table, code block or paragraph carried past a break (default: 0, any)
+
+	Strategy Strategy // How content is shared out between slides (default: StrategyGreedy)
+
+	TableKeyColumn int // 1-based column repeated on every column group of a table too wide for MaxWidth (default: 0, none)
+
+	SlideFrontMatter bool   // Prepend YAML front matter describing each slide to its content
+	SourceName       string // Name of the source file, recorded in slide front matter and the manifest
+	Manifest         bool   // Have Split write a manifest.json describing the slides
+	Index            bool   // Have Split write an index.md linking to the slides
+	NameTemplate     string // text/template for slide file names, run with NameData (default: DefaultNameTemplate)
+
+	DocumentBreaks bool // Start a new slide at each document joined by SplitDocuments
+	TitleSlides    bool // Open each document joined by SplitDocuments with a slide of its title
 }
 
 // Split takes a Markdown file as a byte slice and splits it into smaller files.
+// The files are written through opts.Writer, or into opts.OutDir when no
+// writer is set, followed by the manifest and index when asked for. A writer
+// supplied in opts is not closed.
 func Split(data []byte, opts SplitOptions) error {
+	slides, err := SplitSlides(data, opts)
+	if err != nil {
+		return err
+	}
+	return writeSlides(slides, NewManifest(slides, data, opts.SourceName), opts)
+}
+
+// writeSlides writes the slides, and the manifest and index describing them
+// when asked for, as Split does. Without a writer in opts they go to the out
+// directory, whose writer is closed once they are written.
+func writeSlides(slides []Slide, manifest Manifest, opts SplitOptions) error {
+	if opts.Writer != nil {
+		return writeFiles(opts.Writer, slides, manifest, opts)
+	}
 	if opts.OutDir == "" {
 		opts.OutDir = "."
 	}
+	w, err := NewDirWriter(opts.OutDir)
+	if err != nil {
+		return err
+	}
+	if err := writeFiles(w, slides, manifest, opts); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// writeFiles writes the slides, and the manifest and index when opts asks
+// for them, to w.
+func writeFiles(w SlideWriter, slides []Slide, manifest Manifest, opts SplitOptions) error {
+	for _, slide := range slides {
+		if err := w.WriteFile(slide.Name, slide.Content); err != nil {
+			return err
+		}
+	}
+
+	if opts.Manifest {
+		out, err := manifest.JSON()
+		if err != nil {
+			return err
+		}
+		if err := w.WriteFile(ManifestName, out); err != nil {
+			return err
+		}
+	}
+	if opts.Index {
+		if err := w.WriteFile(IndexName, manifest.Index()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SplitSlides takes a Markdown file as a byte slice and splits it into slides
+// held in memory. Nothing is written to disk. YAML or TOML front matter is
+// left out of the slides, and options under its mdsplit key override opts.
+func SplitSlides(data []byte, opts SplitOptions) ([]Slide, error) {
+	data, err := readFrontMatter(data, &opts)
+	if err != nil {
+		return nil, err
+	}
+	slides, err := splitSource(data, nil, opts)
+	if err != nil {
+		return nil, err
+	}
+	for i := range slides {
+		slides[i].Source = opts.SourceName
+	}
+	if err := nameSlides(slides, opts.NameTemplate, opts.SourceName); err != nil {
+		return nil, err
+	}
+	if opts.SlideFrontMatter {
+		if err := addSlideFrontMatter(slides, map[string][]byte{opts.SourceName: data}, opts); err != nil {
+			return nil, err
+		}
+	}
+	return slides, nil
+}
+
+// splitSource splits data, from which any front matter has been removed, into
+// unnamed slides. When data joins several documents, docs says where each
+// begins.
+func splitSource(data []byte, docs []joinedDocument, opts SplitOptions) ([]Slide, error) {
+	if err := checkStrategy(opts.Strategy); err != nil {
+		return nil, err
+	}
 
 	// Set defaults for font size and DPI
 	if opts.FontSize == 0 {
@@ -75,229 +191,408 @@ func Split(data []byte, opts SplitOptions) error {
 		opts.MaxHeight = 40
 	}
 
-	if err := os.MkdirAll(opts.OutDir, 0755); err != nil {
-		return err
-	}
-
 	parser := goldmark.New(goldmark.WithExtensions(gfm.GFM)).Parser()
 	renderer := markdown.NewRenderer()
 	root := parser.Parse(text.NewReader(data))
 
-	var currentSlide bytes.Buffer
-	slideCount := 1
-	currentLineCount := 0
-
-	for node := root.FirstChild(); node != nil; node = node.NextSibling() {
-		var nodeContent bytes.Buffer
-
-		if err := safeRender(renderer, &nodeContent, data, node); err != nil {
-			return err
+	if opts.Measurer == nil && opts.FontFile != "" {
+		m, err := LoadFontMeasurer(opts.FontFile, opts.MonoFontFile)
+		if err != nil {
+			return nil, err
 		}
+		opts.Measurer = m
+	}
 
-		// Trim leading newlines to avoid double padding accumulated from previous nodes
-		trimmedBytes := bytes.TrimLeft(nodeContent.Bytes(), "\n")
-		nodeContent.Reset()
-		nodeContent.Write(trimmedBytes)
+	notes, err := newNotes(opts)
+	if err != nil {
+		return nil, err
+	}
 
-		nodeLineCount := bytes.Count(nodeContent.Bytes(), []byte{'\n'})
+	s := newSplitter(data, opts, newLayout(opts), renderer, notes)
 
-		// Handle paragraphs that are too long.
-		// fmt.Printf("DEBUG: Current Total: %d, Max: %d, Will Add: %v\n", currentLineCount, opts.MaxHeight, currentLineCount+nodeLineCount >= opts.MaxHeight)
+	// region is the directive governing the blocks being read, and group
+	// collects the blocks of a keep-together region.
+	region := ""
+	var group []block
 
-		// Handle tables that are too long.
-		if node.Kind() == extast.KindTable && nodeLineCount > opts.MaxHeight {
-			// Write the current slide if it has content.
-			if currentSlide.Len() > 0 {
-				if err := writeSlide(opts.OutDir, slideCount, &currentSlide); err != nil {
-					return err
-				}
-				slideCount++
-				currentSlide.Reset()
-				currentLineCount = 0
-			}
+	// next is the joined document due to begin, and doc the one being read.
+	next := 0
+	var doc *joinedDocument
 
-			lines := strings.Split(nodeContent.String(), "\n")
-			// Remove trailing empty lines resulting from Split on string ending with newlines
-			for len(lines) > 0 && lines[len(lines)-1] == "" {
-				lines = lines[:len(lines)-1]
+	for node := root.FirstChild(); node != nil; node = node.NextSibling() {
+		start, _ := getNodeBounds(node)
+		if next < len(docs) && start >= docs[next].start {
+			for next < len(docs) && start >= docs[next].start {
+				next++
+			}
+			doc = &docs[next-1]
+			// Directives do not reach past the end of a document.
+			s.placeGroup(group)
+			group = nil
+			region = ""
+			if opts.DocumentBreaks || opts.TitleSlides {
+				s.breakSlide(BreakDocument)
+				s.headings = nil
 			}
+		}
+		if doc != nil {
+			rewriteLinks(node, doc.linkBase)
+		}
 
-			header := lines[0] + "\n" + lines[1] + "\n"
-			rows := lines[2:]
+		// Break markers end the slide and are left out of it.
+		if isBreakMarker(node, data, opts.BreakOn) {
+			s.placeGroup(group)
+			group = nil
+			s.breakSlide(BreakManual)
+			continue
+		}
 
-			tablePart := 1
-			for len(rows) > 0 {
-				continuationNote := fmt.Sprintf("\n_Table continued (part %d)_", tablePart)
-				chunkSize := opts.MaxHeight - 3 // Account for header and continuation note.
-				if chunkSize <= 0 {
-					chunkSize = 1
-				}
-				if len(rows) <= chunkSize {
-					chunkSize = len(rows)
-				}
+		// Directives open and close regions and are left out of the slides.
+		switch d := directive(node, data); d {
+		case "keep-together", "no-split", "end":
+			s.placeGroup(group)
+			group = nil
+			region = d
+			continue
+		}
 
-				var slideContent bytes.Buffer
-				slideContent.WriteString(header)
-				slideContent.WriteString(strings.Join(rows[:chunkSize], "\n"))
-				slideContent.WriteString("\n")
-				slideContent.WriteString(continuationNote)
+		b, err := renderBlock(renderer, data, node, s.layout)
+		if err != nil {
+			return nil, err
+		}
 
-				if err := writeSlide(opts.OutDir, slideCount, &slideContent); err != nil {
-					return err
-				}
+		switch region {
+		case "keep-together":
+			group = append(group, b)
+		case "no-split":
+			// Each block moves to a new slide if it does not fit, and the
+			// slide then grows as tall as the block needs.
+			s.placeGroup([]block{b})
+		default:
+			s.place(b)
+		}
 
-				rows = rows[chunkSize:]
-				slideCount++
-				tablePart++
-			}
-			continue
+		// A title slide holds nothing but the title.
+		if opts.TitleSlides && doc != nil && start >= 0 && start < doc.body {
+			s.breakSlide(BreakDocument)
 		}
+	}
+	s.placeGroup(group)
 
-		// Handle fenced code blocks that are too long.
-		if node.Kind() == ast.KindFencedCodeBlock && nodeLineCount > opts.MaxHeight {
-			// Write current slide if not empty
-			if currentSlide.Len() > 0 {
-				if err := writeSlide(opts.OutDir, slideCount, &currentSlide); err != nil {
-					return err
-				}
-				slideCount++
-				currentSlide.Reset()
-				currentLineCount = 0
-			}
+	slides := s.finish()
+	if s.err != nil {
+		return nil, s.err
+	}
+	return slides, nil
+}
 
-			lines := strings.Split(nodeContent.String(), "\n")
-			// Remove trailing empty lines
-			for len(lines) > 0 && lines[len(lines)-1] == "" {
-				lines = lines[:len(lines)-1]
-			}
+// place adds a block to the slides, starting a new slide first when the
+// options call for it and splitting blocks too tall for a slide of their own.
+func (s *splitter) place(b block) {
+	node := b.node
+	maxHeight := s.opts.MaxHeight
 
-			if len(lines) >= 2 {
-				startFence := lines[0]
-				endFence := lines[len(lines)-1]
-				codeLines := lines[1 : len(lines)-1]
+	// Start a new slide at headings of the configured levels.
+	if level := headingLevel(node); level > 0 && level <= s.breakLevel {
+		s.breakSlide(BreakHeading)
+	}
 
-				for len(codeLines) > 0 {
-					chunkSize := opts.MaxHeight - 2 // Account for fences
-					if chunkSize <= 0 {
-						chunkSize = 1
-					}
-					if len(codeLines) < chunkSize {
-						chunkSize = len(codeLines)
-					}
+	// Sections go on a slide of their own whatever their size.
+	if s.opts.SplitBySection {
+		s.add(b)
+		return
+	}
 
-					var slideContent bytes.Buffer
-					slideContent.WriteString(startFence)
-					slideContent.WriteString("\n")
-					slideContent.WriteString(strings.Join(codeLines[:chunkSize], "\n"))
-					slideContent.WriteString("\n")
-					slideContent.WriteString(endFence)
-					slideContent.WriteString("\n")
+	// Handle tables that are too wide or too long.
+	if node.Kind() == extast.KindTable {
+		for _, group := range splitTableColumns(b, s.opts.TableKeyColumn, s.layout) {
+			s.placeTable(group)
+		}
+		return
+	}
 
-					if err := writeSlide(opts.OutDir, slideCount, &slideContent); err != nil {
-						return err
-					}
+	// Handle fenced code blocks that are too long.
+	if node.Kind() == ast.KindFencedCodeBlock && b.lines > maxHeight && len(trimmedLines(b.content)) >= 2 {
+		lead := s.startOversized()
+		s.emitParts(NoteCode, b.node, splitCodeBlock(b, s.partHeight(), lead, s.noteRoom(NoteCode, b.lines), s.opts.Strategy, s.limits(), s.layout))
+		return
+	}
 
-					slideCount++
-					codeLines = codeLines[chunkSize:]
-				}
-				continue
-			}
-		}
+	// Handle lists that are too long.
+	if node.Kind() == ast.KindList && b.lines > maxHeight {
+		lead := s.startOversized()
+		s.emitParts(NoteList, b.node, splitList(b, s.source, s.partHeight(), lead, s.noteRoom(NoteList, b.lines), s.layout))
+		return
+	}
 
-		// Handle paragraphs that are too long.
-		if node.Kind() == ast.KindParagraph && nodeLineCount > opts.MaxHeight {
-			// Write the current slide if it has content.
-			if currentSlide.Len() > 0 {
-				if err := writeSlide(opts.OutDir, slideCount, &currentSlide); err != nil {
-					return err
-				}
-				slideCount++
-				currentSlide.Reset()
-				currentLineCount = 0
-			}
+	// Handle blockquotes and alerts that are too long.
+	if node.Kind() == ast.KindBlockquote && b.lines > maxHeight {
+		if q, err := renderQuote(b, s.renderer, s.source, s.layout); err == nil && len(q.children) > 1 {
+			lead := s.startOversized()
+			s.emitParts(NoteBlockquote, b.node, q.split(s.partHeight(), lead, s.noteRoom(NoteBlockquote, b.lines)))
+			return
+		}
+	}
 
-			lines := strings.Split(nodeContent.String(), "\n")
-			// Remove trailing empty lines
-			for len(lines) > 0 && lines[len(lines)-1] == "" {
-				lines = lines[:len(lines)-1]
-			}
+	// Handle paragraphs that are too long.
+	if node.Kind() == ast.KindParagraph && b.lines > maxHeight {
+		lead := s.startOversized()
+		s.emitParts(NoteParagraph, b.node, splitParagraph(b, s.source, s.partHeight(), lead, s.noteRoom(NoteParagraph, b.lines), s.limits(), s.layout))
+		return
+	}
 
-			// Split paragraph into chunks
-			for len(lines) > 0 {
-				chunkSize := opts.MaxHeight
-				if len(lines) < chunkSize {
-					chunkSize = len(lines)
-				}
+	s.fill(b)
+}
 
-				var slideContent bytes.Buffer
-				slideContent.WriteString(strings.Join(lines[:chunkSize], "\n"))
-				// Append newline if needed? strings.Join doesn't add trailing newline.
-				// But original lines didn't have it (Split removed it).
-				// We should add it back?
-				// Paragraphs implies text.
-				slideContent.WriteString("\n")
+// placeTable places a table, or a group of its columns, splitting it between
+// rows when it is too long for a slide.
+func (s *splitter) placeTable(g tableGroup) {
+	b := g.table
+	note := ""
+	if g.columns > 0 {
+		note = s.note(NoteData{Kind: NoteColumns, FirstColumn: g.first, LastColumn: g.last, Columns: g.columns})
+	}
+	space := noteSpace(noteLines(note))
+	if b.lines+space > s.opts.MaxHeight {
+		lead := s.startOversized()
+		s.emitParts(NoteTable, b.node, splitTable(b, s.source, s.partHeight(), lead, s.noteRoom(NoteTable, b.lines), note, s.opts.Strategy, s.limits()))
+		return
+	}
+	if note != "" {
+		b.content = append(bytes.Clone(b.content), note+"\n\n"...)
+		b.lines += space
+	}
+	s.fill(b)
+}
 
-				if err := writeSlide(opts.OutDir, slideCount, &slideContent); err != nil {
-					return err
-				}
+// placeGroup adds the blocks of a keep-together region to the slides as one
+// unit. None of them is split, and they all move to a new slide if they do not
+// fit on the current one. A group taller than a slide gets an oversized slide.
+func (s *splitter) placeGroup(group []block) {
+	if len(group) == 0 {
+		return
+	}
+	s.flush()
+	if level := headingLevel(group[0].node); level > 0 && level <= s.breakLevel {
+		s.breakSlide(BreakHeading)
+	}
+	lines := 0
+	for _, b := range group {
+		lines += b.lines
+	}
+	if !s.opts.SplitBySection && s.lines > 0 && s.lines+lines > s.opts.MaxHeight {
+		s.breakSlide(BreakOverflow)
+	}
+	for _, b := range group {
+		s.add(b)
+	}
+}
 
-				slideCount++
-				lines = lines[chunkSize:]
-			}
+// splitTable splits the rendered table into parts of at most maxHeight
+// lines, repeating the header on each part. The first part shares its slide
+// with lead lines of earlier content. Rows are shared out by strategy and
+// breaks are moved to respect limits. A column note, if not empty, goes
+// under the rows of every part, and note lines are left for a continuation
+// note.
+func splitTable(b block, source []byte, maxHeight, lead, note int, columnNote string, strategy Strategy, limits breakLimits) []slidePart {
+	lines := trimmedLines(b.content)
+	header := lines[0] + "\n" + lines[1] + "\n"
+	rows := lines[2:]
+
+	// Row ranges map rendered rows back to the source when they line up.
+	var rowRanges [][2]int
+	for row := b.node.FirstChild(); row != nil; row = row.NextSibling() {
+		if row.Kind() != extast.KindTableRow {
 			continue
 		}
+		start, stop := sourceRange(row, source)
+		rowRanges = append(rowRanges, [2]int{start, stop})
+	}
+	if len(rowRanges) != len(rows) {
+		rowRanges = nil
+	}
 
-		// write the current slide and start a new one.
-		if currentSlide.Len() > 0 && currentLineCount+nodeLineCount > opts.MaxHeight {
-			if err := writeSlide(opts.OutDir, slideCount, &currentSlide); err != nil {
-				return err
+	c := chunker{
+		count:  len(rows),
+		height: func(first, last int) int { return last - first },
+		room: func(part int) int {
+			room := maxHeight - 2 - noteSpace(note) // Account for header and continuation note.
+			room -= noteSpace(noteLines(columnNote))
+			if part == 0 {
+				room -= lead
 			}
-			slideCount++
-			currentSlide.Reset()
-			currentLineCount = 0
+			return max(room, 1)
+		},
+	}
+	bounds := c.pack(strategy, limits)
+
+	var parts []slidePart
+	for i := 1; i < len(bounds); i++ {
+		first, last := bounds[i-1], bounds[i]
+
+		var slideContent bytes.Buffer
+		slideContent.WriteString(header)
+		slideContent.WriteString(strings.Join(rows[first:last], "\n"))
+		slideContent.WriteString("\n")
+		if columnNote != "" {
+			slideContent.WriteString("\n" + columnNote + "\n")
 		}
 
-		currentSlide.Write(nodeContent.Bytes())
-		currentLineCount += nodeLineCount
+		part := slidePart{content: slideContent.Bytes(), start: b.start, stop: b.stop}
+		if rowRanges != nil {
+			part.start = rowRanges[first][0]
+			part.stop = rowRanges[last-1][1]
+		}
+		parts = append(parts, part)
 	}
+	return parts
+}
 
-	if currentSlide.Len() > 0 {
-		if err := writeSlide(opts.OutDir, slideCount, &currentSlide); err != nil {
-			return err
+// splitCodeBlock splits the rendered fenced code block into parts of at most
+// maxHeight visual lines, repeating the fences on each part. The first part
+// shares its slide with lead lines of earlier content. Lines are shared out
+// by strategy, breaks are moved to respect limits and note lines are left
+// for a continuation note.
+func splitCodeBlock(b block, maxHeight, lead, note int, strategy Strategy, limits breakLimits, lay layout) []slidePart {
+	lines := trimmedLines(b.content)
+	startFence := lines[0]
+	endFence := lines[len(lines)-1]
+	codeLines := lines[1 : len(lines)-1]
+	segments := b.node.Lines()
+
+	heights := make([]int, len(codeLines))
+	for i, line := range codeLines {
+		heights[i] = lay.lineHeight(line, 1, true)
+	}
+
+	c := chunker{
+		count:  len(codeLines),
+		height: prefixHeights(heights),
+		room: func(part int) int {
+			room := maxHeight - 2 - noteSpace(note) // Account for fences and note
+			if part == 0 {
+				room -= lead
+			}
+			return room
+		},
+	}
+	bounds := c.pack(strategy, limits)
+
+	var parts []slidePart
+	for i := 1; i < len(bounds); i++ {
+		first, last := bounds[i-1], bounds[i]
+
+		var slideContent bytes.Buffer
+		slideContent.WriteString(startFence)
+		slideContent.WriteString("\n")
+		slideContent.WriteString(strings.Join(codeLines[first:last], "\n"))
+		slideContent.WriteString("\n")
+		slideContent.WriteString(endFence)
+		slideContent.WriteString("\n")
+
+		part := slidePart{content: slideContent.Bytes(), start: -1, stop: -1}
+		if segments.Len() == len(codeLines) {
+			part.start = segments.At(first).Start
+			part.stop = segments.At(last - 1).Stop
 		}
+		parts = append(parts, part)
 	}
+	return parts
+}
 
-	return nil
+// trimmedLines splits content into lines, dropping the empty lines at the end.
+func trimmedLines(content []byte) []string {
+	lines := strings.Split(string(content), "\n")
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// renderBlock renders a top-level node and measures it.
+func renderBlock(renderer *markdown.Renderer, source []byte, node ast.Node, lay layout) (block, error) {
+	var nodeContent bytes.Buffer
+	if err := safeRender(renderer, &nodeContent, source, node, lay); err != nil {
+		return block{}, err
+	}
+
+	// Trim leading newlines to avoid double padding accumulated from previous nodes
+	content := bytes.TrimLeft(nodeContent.Bytes(), "\n")
+	start, stop := sourceRange(node, source)
+	return block{
+		node:    node,
+		content: content,
+		lines:   lay.lineCount(string(content), node),
+		start:   start,
+		stop:    stop,
+	}, nil
 }
 
-func writeSlide(outDir string, slideCount int, content *bytes.Buffer) error {
-	filename := fmt.Sprintf("slide-%d.md", slideCount)
-	filepath := filepath.Join(outDir, filename)
-	return os.WriteFile(filepath, content.Bytes(), 0644)
+// headingLevel returns the level of n if it is a heading, or 0 otherwise.
+func headingLevel(n ast.Node) int {
+	if h, ok := n.(*ast.Heading); ok {
+		return h.Level
+	}
+	return 0
+}
+
+// blockKind names the kind of block n is, using the note kinds where they
+// apply, such as "heading", NoteParagraph or NoteCode.
+func blockKind(n ast.Node) string {
+	switch n.Kind() {
+	case ast.KindHeading:
+		return "heading"
+	case ast.KindParagraph:
+		return NoteParagraph
+	case ast.KindFencedCodeBlock, ast.KindCodeBlock:
+		return NoteCode
+	case ast.KindList:
+		return NoteList
+	case ast.KindBlockquote:
+		return NoteBlockquote
+	case extast.KindTable:
+		return NoteTable
+	case ast.KindThematicBreak:
+		return "thematic-break"
+	case ast.KindHTMLBlock:
+		return "html"
+	}
+	return strings.ToLower(n.Kind().String())
 }
 
-func safeRender(renderer *markdown.Renderer, w *bytes.Buffer, source []byte, n ast.Node) (err error) {
+// headingText returns the plain text of a heading, without any markup.
+func headingText(n ast.Node, source []byte) string {
+	var text strings.Builder
+	_ = ast.Walk(n, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch t := n.(type) {
+		case *ast.Text:
+			text.Write(t.Segment.Value(source))
+			if t.SoftLineBreak() || t.HardLineBreak() {
+				text.WriteByte(' ')
+			}
+		case *ast.String:
+			text.Write(t.Value)
+		}
+		return ast.WalkContinue, nil
+	})
+	return strings.TrimSpace(text.String())
+}
+
+func safeRender(renderer *markdown.Renderer, w *bytes.Buffer, source []byte, n ast.Node, lay layout) (err error) {
 	defer func() {
 		if r := recover(); r != nil {
 			// Fallback: extract raw lines from source by finding the range covered by the node and its children
 			start, stop := getNodeBounds(n)
 			if start != -1 && stop != -1 {
-				// Expand to full lines
-				for start > 0 && source[start-1] != '\n' {
-					start--
-				}
-				for stop < len(source) && source[stop] != '\n' {
-					stop++
-				}
-				// Include the newline at the end if present
-				if stop < len(source) && source[stop] == '\n' {
-					stop++
-				}
+				start, stop = expandToLines(source, start, stop)
 
 				if start < stop {
 					content := source[start:stop]
 					if n.Kind() == ast.KindParagraph {
-						content = wrapText(content, 60)
+						content = lay.wrapText(content)
 					}
 					w.Write(content)
 					// Append newlines to mimic Block spacing usually added by renderer.
@@ -1,10 +1,16 @@
 package mdsplit
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
 )
 
 func TestSplit(t *testing.T) {
@@ -34,8 +40,8 @@ func TestSplit(t *testing.T) {
 			opts:              SplitOptions{MaxHeight: 40},
 			expectedFileCount: 2,
 			expectedContentCheck: map[string]string{
-				"slide-1.md": "| Header 1 | Header 2 |\n|---|---|\n" + strings.Repeat("| a | b |\n", 37) + "\n_Table continued (part 1)_",
-				"slide-2.md": "| Header 1 | Header 2 |\n|---|---|\n" + strings.Repeat("| a | b |\n", 13) + "\n_Table continued (part 2)_",
+				"slide-1.md": "| Header 1 | Header 2 |\n|---|---|\n" + strings.Repeat("| a | b |\n", 36) + "\n_Table continued (part 1)_",
+				"slide-2.md": "| Header 1 | Header 2 |\n|---|---|\n" + strings.Repeat("| a | b |\n", 14) + "\n_Table continued (part 2)_",
 			},
 		},
 		{
@@ -102,11 +108,293 @@ Final content here.`,
 				"slide-3.md": strings.Repeat("This is a line of text.\n", 20),
 			},
 		},
+		{
+			name:              "heading break level",
+			input:             "# One\n\nText.\n\n## One A\n\nMore.\n\n# Two\n\nEnd.\n",
+			opts:              SplitOptions{MaxHeight: 40, HeadingBreakLevel: 1},
+			expectedFileCount: 2,
+			expectedContentCheck: map[string]string{
+				"slide-1.md": "# One\n\nText.\n\n## One A\n\nMore.",
+				"slide-2.md": "# Two\n\nEnd.",
+			},
+		},
+		{
+			name:              "keep heading with next",
+			input:             "Intro.\n\nMore intro.\n\n## Next\n\nBody text.\n",
+			opts:              SplitOptions{MaxHeight: 6, KeepHeadingWithNext: true},
+			expectedFileCount: 2,
+			expectedContentCheck: map[string]string{
+				"slide-1.md": "Intro.\n\nMore intro.",
+				"slide-2.md": "## Next\n\nBody text.",
+			},
+		},
+		{
+			name:              "keep heading with oversized table",
+			input:             "Intro.\n\n## Data\n\n| A |\n|---|\n" + strings.Repeat("| a |\n", 6),
+			opts:              SplitOptions{MaxHeight: 7, KeepHeadingWithNext: true},
+			expectedFileCount: 4,
+			expectedContentCheck: map[string]string{
+				"slide-1.md": "Intro.",
+				"slide-2.md": "## Data\n\n| A |\n|---|\n| a |\n\n_Table continued (part 1)_",
+			},
+		},
+		{
+			name:              "split by section",
+			input:             "# One\n\n" + strings.Repeat("Line.\n", 20) + "\n## One A\n\nText.\n",
+			opts:              SplitOptions{MaxHeight: 5, SplitBySection: true},
+			expectedFileCount: 2,
+			expectedContentCheck: map[string]string{
+				"slide-2.md": "## One A\n\nText.",
+			},
+		},
+		{
+			name:              "manual breaks",
+			input:             "# A\n\nOne.\n\n---\n\nTwo.\n\n<!-- mdsplit:break -->\n\nThree.\n\n<!-- other comment -->\n\nFour.\n",
+			opts:              SplitOptions{MaxHeight: 40, BreakOn: BreakOnThematicBreak | BreakOnComment},
+			expectedFileCount: 3,
+			expectedContentCheck: map[string]string{
+				"slide-1.md": "# A\n\nOne.",
+				"slide-2.md": "Two.",
+				"slide-3.md": "Three.\n\n<!-- other comment -->\n\nFour.",
+			},
+		},
+		{
+			name:              "comment breaks only",
+			input:             "One.\n\n---\n\nTwo.\n\n<!-- mdsplit:break -->\n\nThree.\n",
+			opts:              SplitOptions{MaxHeight: 40, BreakOn: BreakOnComment},
+			expectedFileCount: 2,
+			expectedContentCheck: map[string]string{
+				"slide-1.md": "One.\n\n---\n\nTwo.",
+				"slide-2.md": "Three.",
+			},
+		},
+		{
+			name:              "keep together",
+			input:             "Intro.\n\n<!-- mdsplit:keep-together -->\n\n| A |\n|---|\n" + strings.Repeat("| a |\n", 6) + "\nShort note.\n\n<!-- mdsplit:end -->\n\nAfter.\n",
+			opts:              SplitOptions{MaxHeight: 6},
+			expectedFileCount: 3,
+			expectedContentCheck: map[string]string{
+				"slide-1.md": "Intro.",
+				"slide-2.md": "| A |\n|---|\n" + strings.Repeat("| a |\n", 6) + "\n\nShort note.",
+				"slide-3.md": "After.",
+			},
+		},
+		{
+			name:              "no split",
+			input:             "One.\n\n<!-- mdsplit:no-split -->\n\n" + strings.Repeat("Line.\n", 10) + "\n<!-- mdsplit:end -->\n\nTwo.\n",
+			opts:              SplitOptions{MaxHeight: 4},
+			expectedFileCount: 3,
+			expectedContentCheck: map[string]string{
+				"slide-1.md": "One.",
+				"slide-2.md": strings.Repeat("Line.\n", 10),
+				"slide-3.md": "Two.",
+			},
+		},
+		{
+			name:              "long ordered list",
+			input:             orderedList(1, 20),
+			opts:              SplitOptions{MaxHeight: 10, ListContinuationNote: true},
+			expectedFileCount: 3,
+			expectedContentCheck: map[string]string{
+				"slide-1.md": orderedList(1, 8) + "\n_List continued (part 1)_",
+				"slide-2.md": orderedList(9, 16) + "\n_List continued (part 2)_",
+				"slide-3.md": orderedList(17, 20) + "\n_List continued (part 3)_",
+			},
+		},
+		{
+			name:              "long nested list item",
+			input:             "- one\n- two\n" + strings.Repeat("  - sub\n", 6) + "- three\n",
+			opts:              SplitOptions{MaxHeight: 5},
+			expectedFileCount: 3,
+			expectedContentCheck: map[string]string{
+				"slide-1.md": "- one",
+				"slide-2.md": "- two\n" + strings.Repeat("  - sub\n", 4),
+				"slide-3.md": "- two\n" + strings.Repeat("  - sub\n", 2) + "- three",
+			},
+		},
+		{
+			name:              "long blockquote",
+			input:             "> one\n>\n> two\n> two\n>\n> three\n",
+			opts:              SplitOptions{MaxHeight: 4},
+			expectedFileCount: 2,
+			expectedContentCheck: map[string]string{
+				"slide-1.md": "> one\n>\n> two\n> two\n",
+				"slide-2.md": "> three\n",
+			},
+		},
+		{
+			name:              "long alert",
+			input:             "> [!WARNING]\n> one\n>\n> two\n>\n> - three\n> - four\n",
+			opts:              SplitOptions{MaxHeight: 4},
+			expectedFileCount: 2,
+			expectedContentCheck: map[string]string{
+				"slide-1.md": "> [!WARNING]\n> one\n>\n> two\n",
+				"slide-2.md": "> [!WARNING]\n> - three\n> - four\n",
+			},
+		},
+		{
+			name:              "paragraph split at sentences",
+			input:             "Alpha one.\nBeta two. Gamma\nthree.\nDelta `code span` four.\n",
+			opts:              SplitOptions{MaxHeight: 2},
+			expectedFileCount: 3,
+			expectedContentCheck: map[string]string{
+				"slide-1.md": "Alpha one.\nBeta two.",
+				"slide-2.md": "Gamma\nthree.",
+				"slide-3.md": "Delta `code span` four.",
+			},
+		},
+		{
+			name:              "paragraph split at words",
+			input:             "one two [three\nfour](five) six\nseven eight\n",
+			opts:              SplitOptions{MaxHeight: 1},
+			expectedFileCount: 4,
+			expectedContentCheck: map[string]string{
+				"slide-1.md": "one two",
+				"slide-2.md": "[three\nfour](five)",
+				"slide-3.md": "six",
+				"slide-4.md": "seven eight",
+			},
+		},
+		{
+			name: "long table with widow control",
+			input: `| Header 1 | Header 2 |
+|---|---|
+` + strings.Repeat("| a | b |\n", 50),
+			opts:              SplitOptions{MaxHeight: 40, MinLinesAfterBreak: 20},
+			expectedFileCount: 2,
+			expectedContentCheck: map[string]string{
+				"slide-1.md": "| Header 1 | Header 2 |\n|---|---|\n" + strings.Repeat("| a | b |\n", 25) + "\n_Table continued (part 1)_",
+				"slide-2.md": "| Header 1 | Header 2 |\n|---|---|\n" + strings.Repeat("| a | b |\n", 25) + "\n_Table continued (part 2)_",
+			},
+		},
+		{
+			name:              "long codeblock with widow control",
+			input:             "```go\n" + strings.Repeat("fmt.Println(\"hello\")\n", 40) + "```",
+			opts:              SplitOptions{MaxHeight: 20, MinLinesAfterBreak: 5},
+			expectedFileCount: 3,
+			expectedContentCheck: map[string]string{
+				"slide-1.md": "```go\n" + strings.Repeat("fmt.Println(\"hello\")\n", 14) + "```",
+				"slide-2.md": "```go\n" + strings.Repeat("fmt.Println(\"hello\")\n", 13) + "```",
+				"slide-3.md": "```go\n" + strings.Repeat("fmt.Println(\"hello\")\n", 13) + "```",
+			},
+		},
+		{
+			name:              "long codeblock balanced",
+			input:             "```go\n" + strings.Repeat("fmt.Println(\"hello\")\n", 40) + "```",
+			opts:              SplitOptions{MaxHeight: 20, Strategy: StrategyBalanced},
+			expectedFileCount: 3,
+			expectedContentCheck: map[string]string{
+				"slide-1.md": "```go\n" + strings.Repeat("fmt.Println(\"hello\")\n", 14) + "```",
+				"slide-2.md": "```go\n" + strings.Repeat("fmt.Println(\"hello\")\n", 13) + "```",
+				"slide-3.md": "```go\n" + strings.Repeat("fmt.Println(\"hello\")\n", 13) + "```",
+			},
+		},
+		{
+			name:              "optimal packing",
+			input:             "a0\na1\na2\n\nb0\nb1\n\nc0\nc1\n\nd0\nd1\nd2\nd3\nd4\n",
+			opts:              SplitOptions{MaxHeight: 7, Strategy: StrategyOptimal},
+			expectedFileCount: 3,
+			expectedContentCheck: map[string]string{
+				"slide-1.md": "a0\na1\na2",
+				"slide-2.md": "b0\nb1\n\nc0\nc1",
+				"slide-3.md": "d0\nd1\nd2\nd3\nd4",
+			},
+		},
+		{
+			name:              "wide table",
+			input:             "| Key | Long header one | Long header two |\n|---|:-:|---|\n| k | a | b |\n",
+			opts:              SplitOptions{MaxHeight: 40, MaxWidth: 300, TableKeyColumn: 1},
+			expectedFileCount: 1,
+			expectedContentCheck: map[string]string{
+				"slide-1.md": "| Key | Long header one |\n| --- | :-: |\n| k | a |\n\n_Columns 1–2 of 3_\n\n| Key | Long header two |\n| --- | --- |\n| k | b |\n\n_Column 3 of 3_",
+			},
+		},
+		{
+			name:              "code notes under heading",
+			input:             "## Setup\n\n```sh\n" + strings.Repeat("echo\n", 4) + "```\n",
+			opts:              SplitOptions{MaxHeight: 6, NoteTemplates: map[string]string{NoteCode: "_{{.Heading}} ({{.Part}}/{{.Total}})_"}},
+			expectedFileCount: 3,
+			expectedContentCheck: map[string]string{
+				"slide-2.md": "```sh\necho\necho\n```\n\n_Setup (1/2)_",
+				"slide-3.md": "```sh\necho\necho\n```\n\n_Setup (2/2)_",
+			},
+		},
+		{
+			name:              "heading breadcrumb",
+			input:             "# Guide\n\n## Setup\n\nOne.\n\nTwo.\n\nThree.\n\n```sh\n" + strings.Repeat("echo\n", 4) + "```\n\n## Usage\n\nRun.\n",
+			opts:              SplitOptions{MaxHeight: 6, HeadingBreadcrumb: true},
+			expectedFileCount: 5,
+			expectedContentCheck: map[string]string{
+				"slide-1.md": "# Guide\n\n## Setup\n\nOne.\n\n",
+				"slide-2.md": "_Guide › Setup (cont.)_\n\nTwo.\n\nThree.\n\n",
+				"slide-3.md": "_Guide › Setup (cont.)_\n\n```sh\necho\necho\n```\n",
+				"slide-4.md": "_Guide › Setup (cont.)_\n\n```sh\necho\necho\n```\n",
+				"slide-5.md": "## Usage\n\nRun.\n",
+			},
+		},
+		{
+			name:              "yaml front matter",
+			input:             "---\ntitle: Deck\nmdsplit:\n  max-height: 2\n---\n# Page 1\n\n# Page 2\n",
+			opts:              SplitOptions{MaxHeight: 40},
+			expectedFileCount: 2,
+			expectedContentCheck: map[string]string{
+				"slide-1.md": "# Page 1\n",
+				"slide-2.md": "# Page 2\n",
+			},
+		},
+		{
+			name:              "toml front matter",
+			input:             "+++\ntitle = \"Deck\"\n+++\nSome content.\n",
+			opts:              SplitOptions{MaxHeight: 40},
+			expectedFileCount: 1,
+			expectedContentCheck: map[string]string{
+				"slide-1.md": "Some content.\n",
+			},
+		},
+		{
+			name:              "thematic breaks like front matter",
+			input:             "---\n# Title\n\nSome text here.\n\n---\n\n# Next\n\nMore.\n",
+			opts:              SplitOptions{MaxHeight: 40},
+			expectedFileCount: 1,
+			expectedContentCheck: map[string]string{
+				"slide-1.md": "---\n\n# Title\n\nSome text here.\n\n---\n\n# Next\n\nMore.\n",
+			},
+		},
+		{
+			name:              "yaml-like text between thematic breaks",
+			input:             "---\n\nIntro: text\n\n---\n\nMore.\n",
+			opts:              SplitOptions{MaxHeight: 40},
+			expectedFileCount: 1,
+			expectedContentCheck: map[string]string{
+				"slide-1.md": "---\n\nIntro: text\n\n---\n\nMore.\n",
+			},
+		},
+		{
+			name:              "name template",
+			input:             "# Page 1\n\nSome content.\n\n# Page 2\n\nMore content.",
+			opts:              SplitOptions{MaxHeight: 5, SourceName: "deck.md", NameTemplate: "{{.Base}}-{{.Number}}-{{.Slug}}{{.Ext}}"},
+			expectedFileCount: 2,
+			expectedContentCheck: map[string]string{
+				"deck-1-page-1.md": "# Page 1\n\nSome content.\n",
+				"deck-2-page-2.md": "# Page 2\n\nMore content.",
+			},
+		},
+		{
+			name:              "stable names",
+			input:             "Opening words.\n\n# Usage\n\nOne.\n\n# Usage\n\nTwo.",
+			opts:              SplitOptions{MaxHeight: 40, HeadingBreakLevel: 1, NameTemplate: StableNameTemplate},
+			expectedFileCount: 3,
+			expectedContentCheck: map[string]string{
+				"top-1.md":     "Opening words.\n",
+				"usage-1.md":   "# Usage\n\nOne.\n",
+				"usage-1-1.md": "# Usage\n\nTwo.",
+			},
+		},
 		{
 			name:              "readme split",
 			input:             readmeContent,
 			opts:              SplitOptions{MaxHeight: 40},
-			expectedFileCount: 4,
+			expectedFileCount: 11,
 			expectedContentCheck: map[string]string{
 				"slide-1.md": `# mdsplit – Markdown Splitting (Go CLI & Library)
 
@@ -119,6 +407,10 @@ Final content here.`,
 - Parses Markdown with ` + "`goldmark`" + ` and splits the result into multiple Markdown files.
 - Intelligently splits content based on a maximum line count.
 - Handles long tables by splitting them and adding a header to each part with a continuation note.
+- Splits long lists between items, keeping ordered-list numbering and nested items intact.
+- Splits long blockquotes and GitHub alerts between their paragraphs, repeating the ` + "`> [!NOTE]`" + ` marker on each part.
+- Leaves YAML or TOML front matter out of the slides and reads per-document options from it.
+- Splits several files, directories or glob patterns at once, each into its own directory.
 - Customizable slide size (vertical and horizontal).
 
 ---
@@ -139,27 +431,14 @@ Requires Go 1.22 or newer.
 
 ---
 
-## CLI usage
-
-` + "```" + `bash
-./mdsplit -in README.md -out ./slides
-` + "```" + `
-
-### Flags`,
-				"slide-4.md": `1. Parse Markdown with [` + "`yuin/goldmark`" + `](https://github.com/yuin/goldmark) and the [` + "`goldmark-gfm`" + `](https://github.com/yuin/goldmark-gfm) extension.
-2. Walk the AST and split the content into multiple smaller Markdown files based on a maximum line count.
-3. If a table is too long, it is split into multiple slides, with the header repeated on each slide.
-4. Write the split Markdown files to the output directory.
-
-Everything happens in memory; there is no HTML renderer or external process.
-
----
+## CLI usage`,
+				"slide-11.md": `---
 
 ## Roadmap
 
 - [ ] Use ` + "`md2png`" + `'s rendering engine to accurately measure slide height.
-- [ ] Implement ` + "`-max-width`" + ` to control the width of the slides.
-- [ ] Intelligent splitting of lists and code blocks.
+- [x] Implement ` + "`-max-width`" + ` to control the width of the slides.
+- [x] Intelligent splitting of lists and code blocks.
 - [ ] Support for different output formats (e.g., a single HTML file with multiple sections).
 - [ ] Configurable themes via YAML/JSON.
 
@@ -213,6 +492,14 @@ Everything happens in memory; there is no HTML renderer or external process.
 	}
 }
 
+func orderedList(from, to int) string {
+	var b strings.Builder
+	for i := from; i <= to; i++ {
+		fmt.Fprintf(&b, "%d. item\n", i)
+	}
+	return b.String()
+}
+
 func countFiles(dir string) int {
 	files, err := os.ReadDir(dir)
 	if err != nil {
@@ -229,3 +516,176 @@ func readReadme(t *testing.T) string {
 	}
 	return string(content)
 }
+
+func TestSplitSlides(t *testing.T) {
+	input := "# Page 1\n\nSome content.\n\n| A | B |\n|---|---|\n" + strings.Repeat("| a | b |\n", 8) + "\n# Page 2\n\nMore content.\n"
+	slides, err := SplitSlides([]byte(input), SplitOptions{MaxHeight: 6})
+	if err != nil {
+		t.Fatalf("SplitSlides failed: %v", err)
+	}
+
+	expected := []struct {
+		reason BreakReason
+		source string
+	}{
+		{BreakStart, "# Page 1\n\nSome content.\n"},
+		{BreakOversized, strings.Repeat("| a | b |\n", 2)},
+		{BreakContinuation, strings.Repeat("| a | b |\n", 2)},
+		{BreakContinuation, strings.Repeat("| a | b |\n", 2)},
+		{BreakContinuation, strings.Repeat("| a | b |\n", 2)},
+		{BreakOversized, "# Page 2\n\nMore content.\n"},
+	}
+	if len(slides) != len(expected) {
+		t.Fatalf("Expected %d slides, but got %d", len(expected), len(slides))
+	}
+	for i, want := range expected {
+		slide := slides[i]
+		if slide.Index != i+1 {
+			t.Errorf("Slide %d has index %d", i+1, slide.Index)
+		}
+		if slide.Reason != want.reason {
+			t.Errorf("Slide %d has reason %q, expected %q", i+1, slide.Reason, want.reason)
+		}
+		if got := input[slide.Start:slide.End]; got != want.source {
+			t.Errorf("Slide %d covers source %q, expected %q", i+1, got, want.source)
+		}
+	}
+}
+
+func TestSplitSlidesFrontMatter(t *testing.T) {
+	input := "---\ntitle: Deck\n---\n# Page 1\n\nSome content.\n"
+	slides, err := SplitSlides([]byte(input), SplitOptions{MaxHeight: 6})
+	if err != nil {
+		t.Fatalf("SplitSlides failed: %v", err)
+	}
+	if len(slides) != 1 {
+		t.Fatalf("Expected 1 slide, but got %d", len(slides))
+	}
+	if got := input[slides[0].Start:slides[0].End]; got != "# Page 1\n\nSome content.\n" {
+		t.Errorf("Slide covers source %q", got)
+	}
+}
+
+func TestSplitSlidesLongListItem(t *testing.T) {
+	input := "10. first\n" + strings.Repeat("    more text here\n", 8) + "11. second\n"
+	slides, err := SplitSlides([]byte(input), SplitOptions{MaxHeight: 4})
+	if err != nil {
+		t.Fatalf("SplitSlides failed: %v", err)
+	}
+	if len(slides) < 2 {
+		t.Fatalf("Expected the item to be split, but got %d slide", len(slides))
+	}
+	for i, slide := range slides[1:] {
+		root := goldmark.New().Parser().Parse(text.NewReader(slide.Content))
+		list, ok := root.FirstChild().(*ast.List)
+		if !ok {
+			t.Errorf("Slide %d starts with %T, expected a list:\n%s", i+2, root.FirstChild(), slide.Content)
+			continue
+		}
+		if list.Start != 10 && list.Start != 11 {
+			t.Errorf("Slide %d list starts at %d:\n%s", i+2, list.Start, slide.Content)
+		}
+	}
+}
+
+func TestSplitSlidesListRanges(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{
+			name:     "items",
+			input:    "- a\n- b\n- c\n- d\n- e\n- f\n- g\n",
+			expected: []string{"- a\n- b\n- c\n- d\n", "- e\n- f\n- g\n"},
+		},
+		{
+			name:     "nested items",
+			input:    "- top\n  - a\n  - b\n  - c\n  - d\n  - e\n- next\n",
+			expected: []string{"- top\n  - a\n  - b\n  - c\n", "  - d\n  - e\n- next\n"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			slides, err := SplitSlides([]byte(tc.input), SplitOptions{MaxHeight: 4})
+			if err != nil {
+				t.Fatalf("SplitSlides failed: %v", err)
+			}
+			var got []string
+			for _, slide := range slides {
+				got = append(got, tc.input[slide.Start:slide.End])
+			}
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("slides cover %q, expected %q", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestSplitSlidesParagraphRanges(t *testing.T) {
+	input := "# Title\n\n" + strings.Repeat("One two three. ", 12) + "\nmore words here.\n\nAfter.\n"
+	slides, err := SplitSlides([]byte(input), SplitOptions{MaxHeight: 4, MaxWidth: 300})
+	if err != nil {
+		t.Fatalf("SplitSlides failed: %v", err)
+	}
+	if len(slides) != 4 {
+		t.Fatalf("Expected 4 slides, but got %d", len(slides))
+	}
+	for _, slide := range slides[1:3] {
+		source := strings.Fields(input[slide.Start:slide.End])
+		if content := strings.Fields(string(slide.Content)); !reflect.DeepEqual(source, content) {
+			t.Errorf("Slide %d covers source %q, expected the words of %q", slide.Index, source, content)
+		}
+	}
+}
+
+func TestSplitSlidesKeepWithNextHeight(t *testing.T) {
+	testCases := []struct {
+		name      string
+		input     string
+		maxHeight int
+	}{
+		{name: "paragraph", input: "Intro.\n\n## Next\n\nl1\nl2\nl3\nl4\n", maxHeight: 6},
+		{name: "table", input: "Intro.\n\n## Next\n\n| A | B |\n|---|---|\n" + strings.Repeat("| a | b |\n", 6), maxHeight: 10},
+		{name: "split table", input: "Intro.\n\n## Next\n\n| A | B |\n|---|---|\n" + strings.Repeat("| a | b |\n", 8), maxHeight: 10},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			slides, err := SplitSlides([]byte(tc.input), SplitOptions{MaxHeight: tc.maxHeight, KeepHeadingWithNext: true})
+			if err != nil {
+				t.Fatalf("SplitSlides failed: %v", err)
+			}
+			for _, slide := range slides {
+				if slide.Lines > tc.maxHeight {
+					t.Errorf("slide %d is %d lines tall:\n%s", slide.Index, slide.Lines, slide.Content)
+				}
+			}
+		})
+	}
+}
+
+func TestSplitSlidesPartHeight(t *testing.T) {
+	testCases := []struct {
+		name  string
+		input string
+	}{
+		{name: "table", input: "| A | B |\n|---|---|\n" + strings.Repeat("| a | b |\n", 30)},
+		{name: "code block", input: "```\n" + strings.Repeat("code\n", 30) + "```\n"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			slides, err := SplitSlides([]byte(tc.input), SplitOptions{MaxHeight: 10})
+			if err != nil {
+				t.Fatalf("SplitSlides failed: %v", err)
+			}
+			if len(slides) < 2 {
+				t.Fatalf("expected the block to be split, got %d slides", len(slides))
+			}
+			for _, slide := range slides {
+				if slide.Lines > 10 {
+					t.Errorf("slide %d is %d lines tall:\n%s", slide.Index, slide.Lines, slide.Content)
+				}
+			}
+		})
+	}
+}
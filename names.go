@@ -0,0 +1,111 @@
+package mdsplit
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// DefaultNameTemplate names slides slide-1.md, slide-2.md and so on.
+const DefaultNameTemplate = "slide-{{.Index}}.md"
+
+// StableNameTemplate names slides by their IDs, such as usage-2.md, so that
+// editing one section leaves the names of the slides of the others alone.
+const StableNameTemplate = "{{.ID}}.md"
+
+// topAnchor stands in for the anchor of the slides before the first heading.
+const topAnchor = "top"
+
+// NameData is what slide file name templates are executed with.
+type NameData struct {
+	Index  int    // 1-based position of the slide
+	Total  int    // Number of slides
+	Number string // Index zero-padded to the width of Total, so names sort in order
+	Base   string // Base name of the source file without its extension, or empty
+	Slug   string // Heading the slide begins under, lower-cased and hyphenated, or empty
+	ID     string // Stable ID of the slide, as in Slide.ID
+	Ext    string // Extension of the source file, or .md when it has none
+}
+
+// nameSlides names every slide by executing the template text, or
+// DefaultNameTemplate when it is empty. Names must be distinct.
+func nameSlides(slides []Slide, text string, source string) error {
+	if text == "" {
+		text = DefaultNameTemplate
+	}
+	tmpl, err := template.New("name").Parse(text)
+	if err != nil {
+		return fmt.Errorf("parsing name template: %w", err)
+	}
+
+	data := NameData{Total: len(slides), Ext: filepath.Ext(source)}
+	if source != "" {
+		data.Base = strings.TrimSuffix(filepath.Base(source), data.Ext)
+	}
+	if data.Ext == "" {
+		data.Ext = ".md"
+	}
+	width := len(strconv.Itoa(len(slides)))
+
+	seen := map[string]int{}
+	for i := range slides {
+		slide := &slides[i]
+		data.Index = slide.Index
+		data.Number = fmt.Sprintf("%0*d", width, slide.Index)
+		data.Slug = slugify(slide.Section)
+		data.ID = slide.ID
+
+		var name bytes.Buffer
+		if err := tmpl.Execute(&name, data); err != nil {
+			return fmt.Errorf("naming slide %d: %w", slide.Index, err)
+		}
+		slide.Name = name.String()
+		if slide.Name == "" {
+			return fmt.Errorf("naming slide %d: the name template gave an empty name", slide.Index)
+		}
+		if other, ok := seen[slide.Name]; ok {
+			return fmt.Errorf("naming slide %d: %q is also the name of slide %d", slide.Index, slide.Name, other)
+		}
+		seen[slide.Name] = slide.Index
+	}
+	return nil
+}
+
+// slugify lower-cases s and joins its runs of letters and digits with
+// hyphens, so that it can go in a file name.
+func slugify(s string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			hyphen = false
+		} else {
+			hyphen = true
+		}
+	}
+	return b.String()
+}
+
+// headingAnchor returns the anchor GitHub gives a heading with text: lower
+// case, with spaces turned into hyphens and punctuation other than hyphens
+// and underscores dropped.
+func headingAnchor(text string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_':
+			b.WriteRune(r)
+		case r == ' ':
+			b.WriteByte('-')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,108 @@
+package mdsplit
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNameSlides(t *testing.T) {
+	slides := make([]Slide, 12)
+	for i := range slides {
+		slides[i].Index = i + 1
+	}
+	slides[0].Section = "Getting Started!"
+	slides[1].Section = "Über & Ümlaut"
+
+	testCases := []struct {
+		name     string
+		template string
+		source   string
+		expected []string // Names of the first three slides
+		err      string
+	}{
+		{name: "default", expected: []string{"slide-1.md", "slide-2.md", "slide-3.md"}},
+		{name: "padded", template: "{{.Number}}{{.Ext}}", source: "docs/deck.markdown", expected: []string{"01.markdown", "02.markdown", "03.markdown"}},
+		{name: "base and slug", template: `{{.Base}}-{{printf "%03d" .Index}}-{{.Slug}}.md`, source: "docs/deck.md", expected: []string{"deck-001-getting-started.md", "deck-002-über-ümlaut.md", "deck-003-.md"}},
+		{name: "stdin", template: "{{.Base}}{{.Index}}{{.Ext}}", expected: []string{"1.md", "2.md", "3.md"}},
+		{name: "duplicate", template: "{{.Slug}}.md", err: `"slide-3.md" is also the name of slide 3`},
+		{name: "syntax", template: "{{.Index", err: "parsing name template"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			slides := append([]Slide{}, slides...)
+			if tc.name == "duplicate" {
+				slides[2].Section, slides[3].Section = "Slide 3", "Slide 3"
+			}
+			err := nameSlides(slides, tc.template, tc.source)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Errorf("nameSlides error = %v, expected %q", err, tc.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("nameSlides failed: %v", err)
+			}
+			var names []string
+			for _, slide := range slides[:3] {
+				names = append(names, slide.Name)
+			}
+			if !reflect.DeepEqual(names, tc.expected) {
+				t.Errorf("names = %q, expected %q", names, tc.expected)
+			}
+		})
+	}
+}
+
+func TestHeadingAnchor(t *testing.T) {
+	testCases := []struct {
+		text, expected string
+	}{
+		{"Getting Started!", "getting-started"},
+		{"Über & Ümlaut", "über--ümlaut"},
+		{"snake_case and kebab-case", "snake_case-and-kebab-case"},
+		{"v1.2 (beta)", "v12-beta"},
+		{"???", ""},
+	}
+	for _, tc := range testCases {
+		if got := headingAnchor(tc.text); got != tc.expected {
+			t.Errorf("headingAnchor(%q) = %q, expected %q", tc.text, got, tc.expected)
+		}
+	}
+}
+
+func TestSlideIDs(t *testing.T) {
+	sections := "# Setup\n\nInstall it.\n\n# Usage\n\n" + strings.Repeat("Run it.\n\n", 6) + "# ???\n\nHuh.\n\n# Top\n\nLast.\n"
+	opts := SplitOptions{MaxHeight: 6, HeadingBreakLevel: 1}
+	slides, err := SplitSlides([]byte(sections), opts)
+	if err != nil {
+		t.Fatalf("SplitSlides failed: %v", err)
+	}
+	var ids []string
+	for _, slide := range slides {
+		ids = append(ids, slide.ID)
+	}
+	expected := []string{"setup-1", "usage-1", "usage-2", "usage-3", "section-1", "top-1-1"}
+	if !reflect.DeepEqual(ids, expected) {
+		t.Fatalf("IDs = %q, expected %q", ids, expected)
+	}
+
+	// Adding to one section leaves the IDs and content of the others alone.
+	edited, err := SplitSlides([]byte("Before it all.\n\n"+strings.Replace(sections, "Install it.", "Install it first.", 1)), opts)
+	if err != nil {
+		t.Fatalf("SplitSlides failed: %v", err)
+	}
+	before := map[string]string{}
+	for _, slide := range slides {
+		before[slide.ID] = string(slide.Content)
+	}
+	for _, slide := range edited {
+		if content, ok := before[slide.ID]; ok && content != string(slide.Content) && slide.ID != "setup-1" {
+			t.Errorf("slide %s changed:\n%s", slide.ID, slide.Content)
+		}
+	}
+	if edited[0].ID != "top-1" || edited[1].ID != "setup-1" {
+		t.Errorf("edited IDs begin %s, %s, expected top-1, setup-1", edited[0].ID, edited[1].ID)
+	}
+}
@@ -0,0 +1,199 @@
+package mdsplit
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// Kinds of split block that continuation notes are written for.
+const (
+	NoteTable      = "table"      // Parts of a table split between rows
+	NoteColumns    = "columns"    // Column groups of a table too wide for a slide
+	NoteList       = "list"       // Parts of a list split between items
+	NoteCode       = "code"       // Parts of a fenced code block
+	NoteParagraph  = "paragraph"  // Parts of a paragraph
+	NoteBlockquote = "blockquote" // Parts of a blockquote or alert
+	NoteBreadcrumb = "breadcrumb" // Heading trail atop slides continuing a section
+)
+
+// noteFuncs are the functions available to note templates.
+var noteFuncs = template.FuncMap{
+	"join": strings.Join,
+}
+
+// NoteData is what continuation note templates are executed with.
+type NoteData struct {
+	Kind     string   // Kind of the split block, such as NoteTable
+	Part     int      // 1-based number of the part the note goes under
+	Total    int      // Number of parts the block was split into
+	Heading  string   // Text of the nearest heading above the block, or empty
+	Headings []string // Text of the headings above the block, outermost first
+
+	FirstColumn int // First column on the part, for NoteColumns
+	LastColumn  int // Last column on the part, for NoteColumns
+	Columns     int // Number of columns in the table, for NoteColumns
+}
+
+// noteCatalog holds the bundled note templates by language and kind.
+var noteCatalog = map[string]map[string]string{
+	"en": {
+		NoteTable:      "_Table continued (part {{.Part}})_",
+		NoteColumns:    "_{{if eq .FirstColumn .LastColumn}}Column {{.FirstColumn}}{{else}}Columns {{.FirstColumn}}–{{.LastColumn}}{{end}} of {{.Columns}}_",
+		NoteList:       "_List continued (part {{.Part}})_",
+		NoteCode:       "_Code continued (part {{.Part}} of {{.Total}})_",
+		NoteParagraph:  "_Continued (part {{.Part}} of {{.Total}})_",
+		NoteBlockquote: "_Quote continued (part {{.Part}} of {{.Total}})_",
+		NoteBreadcrumb: `_{{join .Headings " › "}} (cont.)_`,
+	},
+	"de": {
+		NoteTable:      "_Tabelle fortgesetzt (Teil {{.Part}})_",
+		NoteColumns:    "_{{if eq .FirstColumn .LastColumn}}Spalte {{.FirstColumn}}{{else}}Spalten {{.FirstColumn}}–{{.LastColumn}}{{end}} von {{.Columns}}_",
+		NoteList:       "_Liste fortgesetzt (Teil {{.Part}})_",
+		NoteCode:       "_Code fortgesetzt (Teil {{.Part}} von {{.Total}})_",
+		NoteParagraph:  "_Fortsetzung (Teil {{.Part}} von {{.Total}})_",
+		NoteBlockquote: "_Zitat fortgesetzt (Teil {{.Part}} von {{.Total}})_",
+		NoteBreadcrumb: `_{{join .Headings " › "}} (Forts.)_`,
+	},
+	"ja": {
+		NoteTable:      "_表の続き（{{.Part}}）_",
+		NoteColumns:    "_{{.Columns}}列中{{if eq .FirstColumn .LastColumn}}{{.FirstColumn}}{{else}}{{.FirstColumn}}〜{{.LastColumn}}{{end}}列目_",
+		NoteList:       "_リストの続き（{{.Part}}）_",
+		NoteCode:       "_コードの続き（{{.Part}}/{{.Total}}）_",
+		NoteParagraph:  "_続き（{{.Part}}/{{.Total}}）_",
+		NoteBlockquote: "_引用の続き（{{.Part}}/{{.Total}}）_",
+		NoteBreadcrumb: `_{{join .Headings " › "}}（続き）_`,
+	},
+}
+
+// NoteLanguages returns the languages continuation notes are bundled in.
+func NoteLanguages() []string {
+	var langs []string
+	for lang := range noteCatalog {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// ParseNoteKinds parses a comma separated list of note kinds, such as
+// "code,paragraph".
+func ParseNoteKinds(s string) ([]string, error) {
+	var kinds []string
+	for _, kind := range strings.Split(s, ",") {
+		kind = strings.TrimSpace(kind)
+		if kind == "" {
+			continue
+		}
+		if _, ok := noteCatalog["en"][kind]; !ok {
+			return nil, fmt.Errorf("unknown note kind %q", kind)
+		}
+		kinds = append(kinds, kind)
+	}
+	return kinds, nil
+}
+
+// notes renders the continuation notes enabled for a split.
+type notes struct {
+	templates map[string]*template.Template
+}
+
+// newNotes prepares the notes for tables and column groups, lists when
+// ListContinuationNote is set, breadcrumbs when HeadingBreadcrumb is set, the
+// kinds in ContinuationNotes and any kind given a template in NoteTemplates.
+func newNotes(opts SplitOptions) (notes, error) {
+	lang := opts.NoteLanguage
+	if lang == "" {
+		lang = "en"
+	}
+	catalog, ok := noteCatalog[lang]
+	if !ok {
+		return notes{}, fmt.Errorf("no continuation notes for language %q (have %s)", lang, strings.Join(NoteLanguages(), ", "))
+	}
+
+	text := map[string]string{
+		NoteTable:   catalog[NoteTable],
+		NoteColumns: catalog[NoteColumns],
+	}
+	if opts.ListContinuationNote {
+		text[NoteList] = catalog[NoteList]
+	}
+	if opts.HeadingBreadcrumb {
+		text[NoteBreadcrumb] = catalog[NoteBreadcrumb]
+	}
+	for _, kind := range opts.ContinuationNotes {
+		t, ok := catalog[kind]
+		if !ok {
+			return notes{}, fmt.Errorf("unknown note kind %q", kind)
+		}
+		text[kind] = t
+	}
+	for kind, t := range opts.NoteTemplates {
+		if _, ok := catalog[kind]; !ok {
+			return notes{}, fmt.Errorf("unknown note kind %q", kind)
+		}
+		text[kind] = t
+	}
+
+	n := notes{templates: map[string]*template.Template{}}
+	for kind, t := range text {
+		if t == "" {
+			continue // An empty template turns the note off.
+		}
+		tmpl, err := template.New(kind).Funcs(noteFuncs).Parse(t)
+		if err != nil {
+			return notes{}, fmt.Errorf("parsing %s note: %w", kind, err)
+		}
+		n.templates[kind] = tmpl
+		if _, err := n.render(NoteData{Kind: kind, Part: 1, Total: 1, Heading: "Heading", Headings: []string{"Heading"}}); err != nil {
+			return notes{}, fmt.Errorf("executing %s note: %w", kind, err)
+		}
+	}
+	return n, nil
+}
+
+// render executes the note template for data.Kind, returning "" when the
+// kind has no note.
+func (n notes) render(data NoteData) (string, error) {
+	tmpl, ok := n.templates[data.Kind]
+	if !ok {
+		return "", nil
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return strings.TrimRight(buf.String(), "\n"), nil
+}
+
+// noteHeight returns the number of visual lines note takes on a slide laid
+// out by lay, or 0 when it is empty.
+func noteHeight(note string, lay layout) int {
+	if note == "" {
+		return 0
+	}
+	height := 0
+	for _, line := range strings.Split(note, "\n") {
+		height += lay.lineHeight(line, 1, false)
+	}
+	return height
+}
+
+// noteLines returns the number of lines in note.
+func noteLines(note string) int {
+	if note == "" {
+		return 0
+	}
+	return strings.Count(note, "\n") + 1
+}
+
+// noteSpace returns the room a note of the given lines takes under a part,
+// counting the blank line above it.
+func noteSpace(lines int) int {
+	if lines == 0 {
+		return 0
+	}
+	return lines + 1
+}
@@ -0,0 +1,81 @@
+package mdsplit
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNotes(t *testing.T) {
+	testCases := []struct {
+		name     string
+		opts     SplitOptions
+		data     NoteData
+		expected string
+	}{
+		{name: "table by default", data: NoteData{Kind: NoteTable, Part: 2, Total: 3}, expected: "_Table continued (part 2)_"},
+		{name: "code off by default", data: NoteData{Kind: NoteCode, Part: 1, Total: 2}, expected: ""},
+		{name: "list note", opts: SplitOptions{ListContinuationNote: true}, data: NoteData{Kind: NoteList, Part: 1, Total: 2}, expected: "_List continued (part 1)_"},
+		{name: "german code", opts: SplitOptions{ContinuationNotes: []string{NoteCode}, NoteLanguage: "de"}, data: NoteData{Kind: NoteCode, Part: 1, Total: 2}, expected: "_Code fortgesetzt (Teil 1 von 2)_"},
+		{name: "japanese columns", opts: SplitOptions{NoteLanguage: "ja"}, data: NoteData{Kind: NoteColumns, FirstColumn: 1, LastColumn: 5, Columns: 15}, expected: "_15列中1〜5列目_"},
+		{name: "custom template", opts: SplitOptions{NoteTemplates: map[string]string{NoteParagraph: "{{.Heading}} ({{.Part}}/{{.Total}})"}}, data: NoteData{Kind: NoteParagraph, Part: 1, Total: 2, Heading: "Intro"}, expected: "Intro (1/2)"},
+		{name: "breadcrumb", opts: SplitOptions{HeadingBreadcrumb: true}, data: NoteData{Kind: NoteBreadcrumb, Headings: []string{"Guide", "Setup"}}, expected: "_Guide › Setup (cont.)_"},
+		{name: "template turned off", opts: SplitOptions{NoteTemplates: map[string]string{NoteTable: ""}}, data: NoteData{Kind: NoteTable, Part: 1, Total: 2}, expected: ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			n, err := newNotes(tc.opts)
+			if err != nil {
+				t.Fatalf("newNotes failed: %v", err)
+			}
+			if got, err := n.render(tc.data); err != nil || got != tc.expected {
+				t.Errorf("render = %q, %v, expected %q", got, err, tc.expected)
+			}
+		})
+	}
+}
+
+func TestNotesErrors(t *testing.T) {
+	testCases := []struct {
+		name string
+		opts SplitOptions
+		err  string
+	}{
+		{name: "language", opts: SplitOptions{NoteLanguage: "xx"}, err: `no continuation notes for language "xx"`},
+		{name: "kind", opts: SplitOptions{ContinuationNotes: []string{"image"}}, err: `unknown note kind "image"`},
+		{name: "syntax", opts: SplitOptions{NoteTemplates: map[string]string{NoteCode: "{{.Part"}}, err: "parsing code note"},
+		{name: "field", opts: SplitOptions{NoteTemplates: map[string]string{NoteCode: "{{.Page}}"}}, err: "executing code note"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := newNotes(tc.opts); err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("newNotes error = %v, expected %q", err, tc.err)
+			}
+		})
+	}
+}
+
+func TestSplitNoteHeight(t *testing.T) {
+	// The note grows a line once the part numbers reach two digits.
+	opts := SplitOptions{MaxHeight: 6, NoteTemplates: map[string]string{NoteCode: "Part {{.Part}}{{if ge .Part 10}}\nof {{.Total}}{{end}}"}}
+	input := "```\n" + strings.Repeat("line\n", 40) + "```\n"
+	slides, err := SplitSlides([]byte(input), opts)
+	if err != nil {
+		t.Fatalf("SplitSlides failed: %v", err)
+	}
+	if len(slides) < 10 {
+		t.Fatalf("expected at least 10 parts, got %d", len(slides))
+	}
+	for _, slide := range slides {
+		if slide.Lines > opts.MaxHeight {
+			t.Errorf("slide %d is %d lines tall:\n%s", slide.Index, slide.Lines, slide.Content)
+		}
+	}
+}
+
+func TestSplitNoteError(t *testing.T) {
+	opts := SplitOptions{MaxHeight: 4, NoteTemplates: map[string]string{NoteCode: "{{index .Headings 0}}"}}
+	input := "```\n" + strings.Repeat("line\n", 10) + "```\n"
+	if _, err := SplitSlides([]byte(input), opts); err == nil || !strings.Contains(err.Error(), "executing code note") {
+		t.Errorf("SplitSlides error = %v, expected the note's", err)
+	}
+}
@@ -0,0 +1,111 @@
+package mdsplit
+
+import "fmt"
+
+// Strategy selects how content is shared out between slides.
+type Strategy string
+
+const (
+	// StrategyGreedy fills every slide and every part of a split block as full
+	// as it goes, leaving any remainder for the last one.
+	StrategyGreedy Strategy = "greedy"
+	// StrategyBalanced splits tables and code blocks into as few parts as
+	// StrategyGreedy, but of roughly even height.
+	StrategyBalanced Strategy = "balanced"
+	// StrategyOptimal balances split blocks and chooses the slide breaks
+	// between blocks to minimise the total badness of the document's slides
+	// rather than filling each slide in turn, in the manner of Knuth–Plass
+	// line breaking.
+	StrategyOptimal Strategy = "optimal"
+)
+
+// checkStrategy reports an error for strategies other than the known ones.
+// The empty strategy is StrategyGreedy.
+func checkStrategy(s Strategy) error {
+	switch s {
+	case "", StrategyGreedy, StrategyBalanced, StrategyOptimal:
+		return nil
+	}
+	return fmt.Errorf("unknown strategy %q", s)
+}
+
+// optimalBreaks chooses the slide breaks between blocks of the given heights
+// that minimise total badness, the square of the lines a slide leaves
+// unused. The last slide has no badness, as more content may follow it. The
+// first slide already holds initial lines, and a slide starting with block i
+// gets lead[i] lines above it. Breaking after a block marked in keep, or
+// after the initial lines when keepInitial is set, costs more than any layout
+// that avoids it. Blocks taller than maxHeight get a slide of
+// their own. It returns the indexes of the blocks that start a new slide,
+// where 0 means the initial lines are closed off first.
+func optimalBreaks(heights, lead []int, initial, maxHeight int, keep []bool, keepInitial bool) []int {
+	n := len(heights)
+	penalty := (maxHeight + 1) * (maxHeight + 1) * (n + 1)
+	badness := func(h int) int {
+		if h > maxHeight {
+			return 0
+		}
+		return (maxHeight - h) * (maxHeight - h)
+	}
+
+	// cost[i+1] is the least cost of closing a slide before block i, with
+	// cost[0] for carrying on the slide holding the initial lines. from
+	// records where the slide closed by each break began.
+	const unset = -1
+	cost := make([]int, n+2)
+	from := make([]int, n+2)
+	for i := range cost {
+		cost[i] = unset
+	}
+	cost[0], cost[1] = 0, 0
+	if initial > 0 {
+		cost[1] = badness(initial)
+		if keepInitial {
+			cost[1] += penalty
+		}
+	}
+
+	for end := 1; end <= n; end++ {
+		// Try every start for the slide that ends with block end-1, working
+		// back until the slide overflows.
+		h := 0
+		for start := end - 1; start >= -1; start-- {
+			extra := 0
+			if start >= 0 {
+				h += heights[start]
+				extra = lead[start]
+			} else {
+				if initial == 0 {
+					break // Carrying on an empty slide is the same as starting afresh.
+				}
+				extra = initial
+			}
+			state := start + 1
+			if cost[state] == unset {
+				continue
+			}
+			if h+extra > maxHeight && (end-start > 1 || start < 0) {
+				break
+			}
+			c := cost[state]
+			if end < n {
+				c += badness(h + extra)
+				if keep[end-1] {
+					c += penalty
+				}
+			}
+			if cost[end+1] == unset || c < cost[end+1] {
+				cost[end+1], from[end+1] = c, state
+			}
+		}
+	}
+
+	var starts []int
+	for state := from[n+1]; state > 0; state = from[state] {
+		if state == 1 && initial == 0 {
+			break // There was nothing to close.
+		}
+		starts = append([]int{state - 1}, starts...)
+	}
+	return starts
+}
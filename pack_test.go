@@ -0,0 +1,34 @@
+package mdsplit
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOptimalBreaks(t *testing.T) {
+	testCases := []struct {
+		name     string
+		heights  []int
+		initial  int
+		keep     []bool
+		expected []int
+	}{
+		{name: "fits", heights: []int{2, 3}, expected: nil},
+		{name: "even slides", heights: []int{3, 2, 2, 5}, expected: []int{1, 3}},
+		{name: "close initial", heights: []int{3, 3}, initial: 4, expected: []int{0}},
+		{name: "fill initial", heights: []int{2, 4}, initial: 4, expected: []int{1}},
+		{name: "tall block", heights: []int{1, 9, 1}, expected: []int{1, 2}},
+		{name: "keep heading", heights: []int{3, 1, 3}, keep: []bool{false, true, false}, expected: []int{1}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			keep := tc.keep
+			if keep == nil {
+				keep = make([]bool, len(tc.heights))
+			}
+			if got := optimalBreaks(tc.heights, make([]int, len(tc.heights)), tc.initial, 6, keep, false); !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("optimalBreaks(%v) = %v, expected %v", tc.heights, got, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,512 @@
+package mdsplit
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/rivo/uniseg"
+	"github.com/yuin/goldmark/ast"
+)
+
+// paragraphAtom is a run of paragraph text that is never broken, such as a
+// word or a whole inline code span, link or emphasis span.
+type paragraphAtom struct {
+	start, end  int
+	sentenceEnd bool // Whether a sentence ends after this atom
+}
+
+// splitParagraph splits the rendered paragraph into parts of at most
+// maxHeight visual lines. Parts end at sentence boundaries where that fills
+// at least half the slide, and otherwise at the last word boundary that fits.
+// Inline code, links and emphasis spans are never broken. The first part
+// shares its slide with lead lines of earlier content. Breaks are moved to
+// respect limits and note lines are left for a continuation note. Each part
+// is attributed to the text it holds when the rendered paragraph matches its
+// source, and to the whole paragraph otherwise.
+func splitParagraph(b block, source []byte, maxHeight, lead, note int, limits breakLimits, lay layout) []slidePart {
+	content := strings.TrimRight(string(b.content), "\n")
+	text := strings.TrimLeft(content, "\n")
+	blank := content[:len(content)-len(text)]
+	atoms := paragraphAtoms(text)
+	offsets := paragraphOffsets(b.node, text, source)
+
+	m := newParagraphMeter(text, atoms, len(blank), lay)
+	c := chunker{
+		count:  len(atoms),
+		height: m.height,
+		room: func(part int) int {
+			room := maxHeight - noteSpace(note) // Account for the continuation note.
+			if part == 0 && lead < room {
+				room -= lead
+			}
+			return room
+		},
+	}
+
+	bounds := []int{0}
+	for first := 0; first < len(atoms); {
+		room := c.room(len(bounds) - 1)
+		last := c.fit(first, len(bounds)-1)
+		if last < len(atoms) {
+			for k := last; k > first; k-- {
+				if atoms[k-1].sentenceEnd {
+					if h := c.height(first, k); 2*h >= room && h >= limits.before {
+						last = k
+					}
+					break
+				}
+			}
+		}
+		bounds = append(bounds, last)
+		first = last
+	}
+	bounds = c.balance(bounds, limits)
+
+	var parts []slidePart
+	for i := 1; i < len(bounds); i++ {
+		first, last := bounds[i-1], bounds[i]
+		chunk := text[atoms[first].start:atoms[last-1].end]
+		if last < len(atoms) {
+			// A hard line break at the end of a part would show as a backslash.
+			chunk = strings.TrimSuffix(chunk, "\\")
+		}
+		prefix := ""
+		if i == 1 {
+			prefix = blank
+		}
+		part := slidePart{content: []byte(prefix + chunk + "\n"), start: b.start, stop: b.stop}
+		if offsets != nil {
+			// The paragraph's own range keeps the indentation and newline
+			// around it at the ends.
+			if first > 0 {
+				part.start = offsets[atoms[first].start]
+			}
+			if last < len(atoms) {
+				part.stop = offsets[atoms[last-1].end-1] + 1
+			}
+		}
+		parts = append(parts, part)
+	}
+	return parts
+}
+
+// paragraphOffsets maps each offset of the rendered paragraph text that is
+// not a space to the source. The two are lined up on the bytes that are not
+// spaces, as rendering only changes the spacing of plainly written text and
+// the emphasis markers used. It returns nil when they do not line up.
+func paragraphOffsets(n ast.Node, text string, source []byte) []int {
+	lines := n.Lines()
+	if lines == nil {
+		return nil
+	}
+	var written []int // Source offsets of the bytes that are not spaces
+	for i := 0; i < lines.Len(); i++ {
+		segment := lines.At(i)
+		for pos := segment.Start; pos < segment.Stop; pos++ {
+			if !isSpaceByte(source[pos]) {
+				written = append(written, pos)
+			}
+		}
+	}
+	offsets := make([]int, len(text))
+	k := 0
+	for i := 0; i < len(text); i++ {
+		if isSpaceByte(text[i]) {
+			continue
+		}
+		if k == len(written) || !sameByte(source[written[k]], text[i]) {
+			return nil
+		}
+		offsets[i] = written[k]
+		k++
+	}
+	if k != len(written) {
+		return nil
+	}
+	return offsets
+}
+
+// sameByte reports whether a source byte is rendered as c.
+func sameByte(source, c byte) bool {
+	return source == c || source == '_' && c == '*'
+}
+
+func isSpaceByte(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// paragraphPiece is a run of paragraph text without spaces that lies within
+// one atom. A word broken between atoms, as text without spaces is, is made
+// of several pieces.
+type paragraphPiece struct {
+	end       int     // Offset just past the piece in the text
+	width     float64 // Width of the piece as displayed
+	bare      float64 // Width without the backslash ending the piece, if it has one
+	backslash bool    // Whether the piece ends with a backslash
+	glued     bool    // Whether the piece continues the word of the piece before
+	hardBreak bool    // Whether a hard line break follows the piece
+	spaced    bool    // Whether the break is made by two spaces, which keep a backslash shown
+}
+
+// paragraphMeter measures runs of a paragraph's atoms as the layout's
+// paragraphHeight would. Each piece is measured once, and the heights of the
+// runs starting at an atom are read off one pass over the pieces after it,
+// rather than by laying every candidate run out again.
+type paragraphMeter struct {
+	lay      layout
+	atoms    []paragraphAtom
+	blank    int              // Blank lines above the paragraph, on the first part only
+	pieces   []paragraphPiece // Pieces of all the atoms, in order
+	starts   []int            // Index of the first piece of each atom, and len(pieces)
+	newlines []int            // Number of newlines before each offset of the text
+	runs     map[int]*paragraphRun
+}
+
+func newParagraphMeter(text string, atoms []paragraphAtom, blank int, lay layout) *paragraphMeter {
+	m := &paragraphMeter{lay: lay, atoms: atoms, blank: blank, runs: map[int]*paragraphRun{}}
+	if lay.width <= 0 {
+		// Without wrapping a run is as tall as its source lines.
+		m.newlines = make([]int, len(text)+1)
+		for i := 0; i < len(text); i++ {
+			m.newlines[i+1] = m.newlines[i]
+			if text[i] == '\n' {
+				m.newlines[i+1]++
+			}
+		}
+		return m
+	}
+	for _, atom := range atoms {
+		m.starts = append(m.starts, len(m.pieces))
+		for pos := atom.start; pos < atom.end; {
+			i := strings.IndexFunc(text[pos:atom.end], func(r rune) bool { return !unicode.IsSpace(r) })
+			if i < 0 {
+				break
+			}
+			start := pos + i
+			end := atom.end
+			if i := strings.IndexFunc(text[start:atom.end], unicode.IsSpace); i >= 0 {
+				end = start + i
+			}
+			word := text[start:end]
+			p := paragraphPiece{end: end, width: lay.wordWidth(word, 1)}
+			p.glued = len(m.pieces) > 0 && m.pieces[len(m.pieces)-1].end == start
+			if strings.HasSuffix(word, "\\") {
+				p.backslash = true
+				p.bare = lay.wordWidth(strings.TrimSuffix(word, "\\"), 1)
+			}
+			// A piece ending its line is followed by a hard line break when the
+			// line ends with two spaces or a backslash.
+			eol := end
+			for eol < len(text) && text[eol] != '\n' && unicode.IsSpace(rune(text[eol])) {
+				eol++
+			}
+			if eol < len(text) && text[eol] == '\n' {
+				p.hardBreak = strings.HasSuffix(text[:eol], "  ") || p.backslash
+				p.spaced = strings.HasSuffix(text[:eol], "  ")
+			}
+			m.pieces = append(m.pieces, p)
+			pos = end
+		}
+	}
+	m.starts = append(m.starts, len(m.pieces))
+	return m
+}
+
+// height returns the visual lines atoms[first:last] take.
+func (m *paragraphMeter) height(first, last int) int {
+	blank := 0
+	if first == 0 {
+		blank = m.blank
+	}
+	if m.lay.width <= 0 {
+		return blank + 1 + m.newlines[m.atoms[last-1].end] - m.newlines[m.atoms[first].start]
+	}
+	r := m.runs[first]
+	if r == nil {
+		r = &paragraphRun{first: first, read: first, w: m.lay.newWrapper(1, "")}
+		m.runs[first] = r
+	}
+	for r.read < last {
+		r.readAtom(m)
+	}
+	i := sort.Search(len(r.marks), func(i int) bool { return r.marks[i].last > last })
+	return blank + r.marks[i-1].height
+}
+
+// paragraphRun is the layout of the atoms read so far from the one a run
+// starts at.
+type paragraphRun struct {
+	first, read int     // Atom the run starts at, and the atom after those read
+	w           wrapper // Lines of the flow being read
+	flows       int     // Visual lines of the flows ended by hard line breaks
+	word        float64 // Width of the word being read
+	pieces      int     // Pieces of the word being read, or 0 between words
+	marks       []heightMark
+}
+
+// heightMark records that runs ending at last and after are height lines
+// tall, up to the next mark.
+type heightMark struct {
+	last, height int
+}
+
+// readAtom reads the next atom and marks the height of the run ending with
+// it.
+func (r *paragraphRun) readAtom(m *paragraphMeter) {
+	var p paragraphPiece
+	for i := m.starts[r.read]; i < m.starts[r.read+1]; i++ {
+		p = m.pieces[i]
+		if !p.glued || i == m.starts[r.first] {
+			r.endWord()
+		}
+		if p.hardBreak && p.backslash && !p.spaced {
+			r.addPiece(p.bare, p.bare == 0)
+		} else {
+			r.addPiece(p.width, false)
+		}
+		if p.hardBreak {
+			r.endWord()
+			r.flows += max(1, r.w.total())
+			r.w = m.lay.newWrapper(1, "")
+		}
+	}
+	r.read++
+
+	// A run ending with a backslash ends with a hard line break, whose
+	// backslash takes no room.
+	w, word, pieces := r.w, r.word, r.pieces
+	if p.backslash && !p.hardBreak {
+		word -= p.width - p.bare
+		if pieces == 1 && p.bare == 0 {
+			pieces = 0
+		}
+	}
+	if pieces > 0 {
+		w.add(word)
+	}
+	height := r.flows + w.total()
+	if n := len(r.marks); n == 0 || r.marks[n-1].height != height {
+		r.marks = append(r.marks, heightMark{last: r.read, height: height})
+	}
+}
+
+// addPiece adds a piece of the given width to the word being read. An empty
+// piece, a lone backslash, starts no word.
+func (r *paragraphRun) addPiece(width float64, empty bool) {
+	if r.pieces == 0 && empty {
+		return
+	}
+	r.word += width
+	r.pieces++
+}
+
+// endWord places the word being read, if any.
+func (r *paragraphRun) endWord() {
+	if r.pieces > 0 {
+		r.w.add(r.word)
+	}
+	r.word, r.pieces = 0, 0
+}
+
+// paragraphAtoms breaks text at the line break opportunities outside
+// inline spans and marks the atoms that end a sentence. Text is broken at
+// spaces, and between wide characters for scripts written without them.
+func paragraphAtoms(text string) []paragraphAtom {
+	protected := inlineSpans(text)
+	var atoms []paragraphAtom
+	start := 0
+	state := -1
+	for offset, rest := 0, text; len(rest) > 0; {
+		var segment string
+		segment, rest, _, state = uniseg.FirstLineSegmentInString(rest, state)
+		offset += len(segment)
+		if len(rest) > 0 && !canBreak(text, offset, protected) {
+			continue
+		}
+		end := start + len(strings.TrimRightFunc(text[start:offset], unicode.IsSpace))
+		if end > start {
+			atoms = append(atoms, paragraphAtom{start: start, end: end})
+		}
+		start = offset
+	}
+
+	// Sentence boundaries fall after the spaces that follow a sentence, or
+	// before closing emphasis markers, so match them loosely to atoms. Soft
+	// line breaks are spaces in Markdown, not paragraph separators.
+	var ends []int
+	state = -1
+	for offset, rest := 0, strings.ReplaceAll(text, "\n", " "); len(rest) > 0; {
+		var sentence string
+		sentence, rest, state = uniseg.FirstSentenceInString(rest, state)
+		offset += len(sentence)
+		ends = append(ends, offset)
+	}
+	for i := range atoms[:max(0, len(atoms)-1)] {
+		from := atoms[i].start + len(strings.TrimRight(text[atoms[i].start:atoms[i].end], "*_~"))
+		to := atoms[i+1].start
+		j := sort.SearchInts(ends, from)
+		atoms[i].sentenceEnd = j < len(ends) && ends[j] <= to
+	}
+	return atoms
+}
+
+// canBreak reports whether text may be broken at offset: after a space, or
+// between wide characters, and never inside an inline span.
+func canBreak(text string, offset int, protected []bool) bool {
+	if protected[offset-1] && protected[offset] {
+		return false
+	}
+	before, _ := utf8.DecodeLastRuneInString(text[:offset])
+	after, _ := utf8.DecodeRuneInString(text[offset:])
+	return unicode.IsSpace(before) || isWide(before) || isWide(after)
+}
+
+// inlineSpans reports for each byte of text whether it lies inside an inline
+// code span, link, image, autolink, inline HTML tag or emphasis span.
+func inlineSpans(text string) []bool {
+	protected := make([]bool, len(text))
+	mark := func(start, end int) {
+		for i := start; i < end; i++ {
+			protected[i] = true
+		}
+	}
+	for i := 0; i < len(text); i++ {
+		var end int
+		switch text[i] {
+		case '`':
+			end = codeSpanEnd(text, i)
+		case '[':
+			end = linkEnd(text, i)
+		case '<':
+			if i+1 < len(text) && (isWordByte(text[i+1]) || text[i+1] == '/' || text[i+1] == '!') {
+				end = strings.IndexByte(text[i:], '>') + i + 1
+			}
+		case '*', '_', '~':
+			if end = emphasisEnd(text, i); end == 0 {
+				i += runLength(text, i, text[i]) - 1 // Skip the whole delimiter run.
+			}
+		case '\\':
+			i++ // Skip the escaped character.
+			continue
+		}
+		if end > i {
+			mark(i, end)
+			i = end - 1
+		}
+	}
+	return protected
+}
+
+// runLength returns the number of consecutive c bytes at text[i:].
+func runLength(text string, i int, c byte) int {
+	n := 0
+	for i+n < len(text) && text[i+n] == c {
+		n++
+	}
+	return n
+}
+
+// codeSpanEnd returns the end of the code span opening at i, or 0 if the
+// backticks are not closed.
+func codeSpanEnd(text string, i int) int {
+	n := runLength(text, i, '`')
+	for j := i + n; j < len(text); {
+		if text[j] != '`' {
+			j++
+			continue
+		}
+		m := runLength(text, j, '`')
+		if m == n {
+			return j + m
+		}
+		j += m
+	}
+	return 0
+}
+
+// linkEnd returns the end of the link or reference opening with the bracket
+// at i, including any destination or label that follows, or 0 if the
+// bracket is not closed.
+func linkEnd(text string, i int) int {
+	end := closingBracket(text, i, '[', ']')
+	if end == 0 || end >= len(text) {
+		return end
+	}
+	switch text[end] {
+	case '(':
+		if dest := closingBracket(text, end, '(', ')'); dest > 0 {
+			return dest
+		}
+	case '[':
+		if label := closingBracket(text, end, '[', ']'); label > 0 {
+			return label
+		}
+	}
+	return end
+}
+
+// closingBracket returns the position after the bracket closing the one at
+// i, allowing nesting and skipping escapes and code spans, or 0 if there is
+// none.
+func closingBracket(text string, i int, open, close byte) int {
+	depth := 0
+	for j := i; j < len(text); j++ {
+		switch text[j] {
+		case '\\':
+			j++
+		case '`':
+			if end := codeSpanEnd(text, j); end > 0 {
+				j = end - 1
+			}
+		case open:
+			depth++
+		case close:
+			depth--
+			if depth == 0 {
+				return j + 1
+			}
+		}
+	}
+	return 0
+}
+
+// emphasisEnd returns the end of the emphasis or strikethrough span opening
+// with the delimiter run at i, or 0 if the run does not open a span that is
+// closed by a run of the same length.
+func emphasisEnd(text string, i int) int {
+	c := text[i]
+	n := runLength(text, i, c)
+	if i+n >= len(text) || unicode.IsSpace(rune(text[i+n])) {
+		return 0 // Not left-flanking.
+	}
+	if c == '_' && i > 0 && isWordByte(text[i-1]) {
+		return 0 // Underscores inside words are not emphasis.
+	}
+	for j := i + n; j < len(text); {
+		switch text[j] {
+		case '\\':
+			j += 2
+			continue
+		case '`':
+			if end := codeSpanEnd(text, j); end > 0 {
+				j = end
+				continue
+			}
+		case c:
+			m := runLength(text, j, c)
+			if m == n && !unicode.IsSpace(rune(text[j-1])) {
+				return j + m
+			}
+			j += m
+			continue
+		}
+		j++
+	}
+	return 0
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
@@ -0,0 +1,86 @@
+package mdsplit
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/yuin/goldmark/ast"
+)
+
+func TestParagraphAtoms(t *testing.T) {
+	testCases := []struct {
+		name      string
+		text      string
+		atoms     []string
+		sentences []string // Atoms a sentence ends after
+	}{
+		{
+			name:      "sentences",
+			text:      "One two.\nThree! Four",
+			atoms:     []string{"One", "two.", "Three!", "Four"},
+			sentences: []string{"two.", "Three!"},
+		},
+		{
+			name:  "inline spans",
+			text:  "a `b c` [d e](f g) **h i** <span class=\"j\">k</span>",
+			atoms: []string{"a", "`b c`", "[d e](f g)", "**h i**", "<span class=\"j\">k</span>"},
+		},
+		{
+			name:      "sentence inside emphasis",
+			text:      "*Done.* Next",
+			atoms:     []string{"*Done.*", "Next"},
+			sentences: []string{"*Done.*"},
+		},
+		{
+			name:  "unclosed delimiters",
+			text:  "2 * 3 and snake_case_name",
+			atoms: []string{"2", "*", "3", "and", "snake_case_name"},
+		},
+		{
+			name:      "wide characters",
+			text:      "文です。次",
+			atoms:     []string{"文", "で", "す。", "次"},
+			sentences: []string{"す。"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var atoms, sentences []string
+			for _, a := range paragraphAtoms(tc.text) {
+				atoms = append(atoms, tc.text[a.start:a.end])
+				if a.sentenceEnd {
+					sentences = append(sentences, tc.text[a.start:a.end])
+				}
+			}
+			if !reflect.DeepEqual(atoms, tc.atoms) {
+				t.Errorf("atoms = %q, expected %q", atoms, tc.atoms)
+			}
+			if !reflect.DeepEqual(sentences, tc.sentences) {
+				t.Errorf("sentence ends = %q, expected %q", sentences, tc.sentences)
+			}
+		})
+	}
+}
+
+func TestParagraphMeter(t *testing.T) {
+	text := "Some words and a [long link](https://example.com/x) here.\\\n" +
+		"A `code span` line  \nwith a hard break, then 文です。次 and\n" +
+		"averyveryverylongwordthatoverflows *emph is* done\\\nend \\\nodd\\  \nlast"
+	atoms := paragraphAtoms(text)
+	node := ast.NewParagraph()
+	for _, width := range []int{0, 80, 120, 600} {
+		lay := newLayout(SplitOptions{MaxWidth: width, FontSize: 12, DPI: 96})
+		m := newParagraphMeter(text, atoms, 1, lay)
+		for first := range atoms {
+			for last := first + 1; last <= len(atoms); last++ {
+				chunk := text[atoms[first].start:atoms[last-1].end] + "\n"
+				if first == 0 {
+					chunk = "\n" + chunk
+				}
+				if got, expected := m.height(first, last), lay.lineCount(chunk, node); got != expected {
+					t.Errorf("width %d: height(%d, %d) = %d, expected %d for %q", width, first, last, got, expected, chunk)
+				}
+			}
+		}
+	}
+}
@@ -16,17 +16,15 @@ echo "Creating output directory: $OUTPUT_DIR"
 rm -rf "$OUTPUT_DIR"
 mkdir -p "$OUTPUT_DIR"
 
-# Generate outputs with default settings
+# Generate outputs with default settings, one directory per sample
+SAMPLES=()
 for f in samples/*.md; do
-  if [ "$(basename "$f")" = "README.md" ]; then
-      continue
+  if [ "$(basename "$f")" != "README.md" ]; then
+      SAMPLES+=("$f")
   fi
-  FILENAME=$(basename "$f")
-  DIRNAME="${FILENAME%.*}"
-  echo "Splitting $f into $OUTPUT_DIR/$DIRNAME (default)..."
-  mkdir -p "$OUTPUT_DIR/$DIRNAME"
-  ./mdsplit -in "$f" -out "$OUTPUT_DIR/$DIRNAME"
 done
+echo "Splitting ${#SAMPLES[@]} samples into $OUTPUT_DIR (default)..."
+./mdsplit -out "$OUTPUT_DIR" "${SAMPLES[@]}"
 
 # Generate outputs with different template sizes
 TEMPLATE_SIZES=("card" "horizontal-card" "presentation" "a4")
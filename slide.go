@@ -0,0 +1,504 @@
+package mdsplit
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"slices"
+
+	markdown "github.com/teekennedy/goldmark-markdown"
+	"github.com/yuin/goldmark/ast"
+)
+
+// BreakReason describes why a slide begins where it does.
+type BreakReason string
+
+const (
+	// BreakStart marks the first slide of a document.
+	BreakStart BreakReason = "start"
+	// BreakOverflow marks a slide started because the next block did not fit on the previous one.
+	BreakOverflow BreakReason = "overflow"
+	// BreakOversized marks a slide started around a block too tall for a single slide.
+	BreakOversized BreakReason = "oversized"
+	// BreakContinuation marks a slide continuing a block split across slides.
+	BreakContinuation BreakReason = "continuation"
+	// BreakHeading marks a slide started at a heading.
+	BreakHeading BreakReason = "heading"
+	// BreakManual marks a slide started at a break marker written by the author.
+	BreakManual BreakReason = "manual"
+	// BreakDocument marks a slide started at a document joined by SplitDocuments, or at its title.
+	BreakDocument BreakReason = "document"
+)
+
+// Slide is a single slide produced by SplitSlides.
+type Slide struct {
+	Index   int         // 1-based position of the slide
+	Name    string      // File name the slide is written to
+	Content []byte      // Markdown content of the slide
+	Start   int         // Byte offset of the first source byte on the slide, or -1 if unknown
+	End     int         // Byte offset just past the last source byte on the slide, or -1 if unknown
+	Reason  BreakReason // Why the break before this slide happened
+	Section string      // Text of the heading the slide begins under, or empty
+	Source  string      // Name of the source file the slide begins in, or empty
+	Anchor  string      // Anchor of the heading the slide begins under, as GitHub makes it, or empty
+	ID      string      // Anchor, or "top" before any heading, and the slide's 1-based position under it, such as "usage-2"
+
+	Headings []string // Trail of headings the slide begins under, outermost first
+	Kinds    []string // Kinds of block on the slide in order of appearance, such as "heading" or NoteTable
+	Lines    int      // Estimated height of the slide in lines
+}
+
+// block is a rendered top-level node.
+type block struct {
+	node        ast.Node
+	content     []byte
+	lines       int
+	start, stop int
+}
+
+// slidePart is a piece of an oversized block that gets a slide of its own.
+type slidePart struct {
+	content     []byte
+	start, stop int
+}
+
+// splitter accumulates blocks into slides.
+type splitter struct {
+	source       []byte
+	opts         SplitOptions
+	layout       layout
+	renderer     *markdown.Renderer
+	notes        notes
+	breakLevel   int
+	slides       []Slide
+	current      bytes.Buffer
+	lines        int
+	start, stop  int
+	reason       BreakReason
+	keepWithNext bool
+	tail         *slideTail
+	pending      []block         // Blocks waiting to be packed by StrategyOptimal
+	headings     []heading       // Headings above the current slide, outermost first
+	path         []string        // Trail of headings the current slide begins under
+	anchor       string          // Anchor of the heading the current slide begins under
+	anchors      map[string]bool // Anchors given to the headings passed, and topAnchor
+	kinds        []string        // Kind of each block on the current slide
+	err          error           // First error executing a note template
+}
+
+// heading is a heading the splitter has passed.
+type heading struct {
+	level  int
+	text   string
+	anchor string
+}
+
+// slideTail records the headings at the bottom of the current slide.
+type slideTail struct {
+	offset, lines int    // Where the headings begin in the slide and how tall they are
+	blocks        int    // Number of headings
+	start, stop   int    // Source range of the headings
+	before        [2]int // Source range of the slide without the headings
+}
+
+func newSplitter(source []byte, opts SplitOptions, lay layout, renderer *markdown.Renderer, notes notes) *splitter {
+	s := &splitter{
+		source:       source,
+		opts:         opts,
+		layout:       lay,
+		renderer:     renderer,
+		notes:        notes,
+		breakLevel:   opts.HeadingBreakLevel,
+		start:        -1,
+		stop:         -1,
+		reason:       BreakStart,
+		keepWithNext: opts.KeepHeadingWithNext,
+		anchors:      map[string]bool{topAnchor: true},
+	}
+	if opts.SplitBySection && s.breakLevel == 0 {
+		s.breakLevel = 6
+	}
+	return s
+}
+
+// add appends a block to the current slide, after any pending blocks.
+func (s *splitter) add(b block) {
+	s.flush()
+	empty := s.current.Len() == 0
+	if empty && len(s.slides) > 0 {
+		if space := s.breadcrumbSpace(b); space > 0 {
+			s.current.WriteString(s.breadcrumb() + "\n\n")
+			s.lines += space
+		}
+	}
+	if level := headingLevel(b.node); level > 0 {
+		text := headingText(b.node, s.source)
+		s.headings = pushHeading(s.headings, heading{level: level, text: text, anchor: s.uniqueAnchor(text)})
+		if s.tail == nil {
+			s.tail = &slideTail{offset: s.current.Len(), start: -1, stop: -1, before: [2]int{s.start, s.stop}}
+		}
+		s.tail.lines += b.lines
+		s.tail.blocks++
+		s.tail.start, s.tail.stop = widenRange(s.tail.start, s.tail.stop, b.start, b.stop)
+	} else {
+		s.tail = nil
+	}
+	if empty {
+		s.path = s.headingPath()
+		s.anchor = s.innermostAnchor()
+	}
+	s.kinds = append(s.kinds, blockKind(b.node))
+	s.current.Write(b.content)
+	s.lines += b.lines
+	s.start, s.stop = widenRange(s.start, s.stop, b.start, b.stop)
+}
+
+// pushHeading returns the headings in force after h, dropping those at its
+// level or below. The headings passed in are left as they were.
+func pushHeading(headings []heading, h heading) []heading {
+	n := len(headings)
+	for n > 0 && headings[n-1].level >= h.level {
+		n--
+	}
+	return append(slices.Clip(headings[:n]), h)
+}
+
+// breakSlide closes the current slide, if it has content, and records why the
+// next slide begins. When keeping headings with the next block, headings at
+// the bottom of a slide closed for lack of room move on to the next one.
+func (s *splitter) breakSlide(reason BreakReason) {
+	s.flush()
+	if s.current.Len() == 0 {
+		if len(s.slides) > 0 {
+			s.reason = reason
+		}
+		return
+	}
+	tail := s.tail
+	carry := s.keepWithNext && tail != nil && tail.offset > 0 && (reason == BreakOverflow || reason == BreakOversized)
+
+	slide := Slide{
+		Content:  bytes.Clone(s.current.Bytes()),
+		Start:    s.start,
+		End:      s.stop,
+		Reason:   s.reason,
+		Headings: s.path,
+		Anchor:   s.anchor,
+		Kinds:    s.kinds,
+		Lines:    s.lines,
+	}
+	var carried []byte
+	if carry {
+		carried = slide.Content[tail.offset:]
+		slide.Content = slide.Content[:tail.offset:tail.offset]
+		slide.Start, slide.End = tail.before[0], tail.before[1]
+		slide.Kinds = slide.Kinds[:len(slide.Kinds)-tail.blocks]
+		slide.Lines -= tail.lines
+	}
+	s.appendSlide(slide)
+
+	s.current.Reset()
+	s.lines = 0
+	s.start, s.stop = -1, -1
+	s.tail = nil
+	s.kinds = nil
+	s.reason = reason
+	if carry {
+		s.current.Write(carried)
+		s.lines = tail.lines
+		s.start, s.stop = tail.start, tail.stop
+		s.tail = &slideTail{lines: tail.lines, blocks: tail.blocks, start: tail.start, stop: tail.stop, before: [2]int{-1, -1}}
+		s.kinds = slices.Repeat([]string{"heading"}, tail.blocks)
+		s.path = s.headingPath()
+		s.anchor = s.innermostAnchor()
+	}
+}
+
+// headingPath returns the text of the headings passed, outermost first.
+func (s *splitter) headingPath() []string {
+	var path []string
+	for _, h := range s.headings {
+		path = append(path, h.text)
+	}
+	return path
+}
+
+// innermostHeading returns the text of the nearest heading passed, or "".
+func (s *splitter) innermostHeading() string {
+	if len(s.headings) == 0 {
+		return ""
+	}
+	return s.headings[len(s.headings)-1].text
+}
+
+// innermostAnchor returns the anchor of the nearest heading passed, or "".
+func (s *splitter) innermostAnchor() string {
+	if len(s.headings) == 0 {
+		return ""
+	}
+	return s.headings[len(s.headings)-1].anchor
+}
+
+// uniqueAnchor returns the anchor of a heading with text, suffixed with -1,
+// -2 and so on when an earlier heading already has it, as GitHub does.
+// Headings without letters or digits are anchored as "section".
+func (s *splitter) uniqueAnchor(text string) string {
+	base := cmp.Or(headingAnchor(text), "section")
+	anchor := base
+	for i := 1; s.anchors[anchor]; i++ {
+		anchor = fmt.Sprintf("%s-%d", base, i)
+	}
+	s.anchors[anchor] = true
+	return anchor
+}
+
+// fill adds a block that may go on the current slide or start the next one.
+// With StrategyOptimal the block waits until the run of such blocks ends, so
+// the breaks between them can be chosen together.
+func (s *splitter) fill(b block) {
+	if s.opts.Strategy == StrategyOptimal {
+		s.pending = append(s.pending, b)
+		return
+	}
+	if s.lines > 0 && s.lines+b.lines > s.opts.MaxHeight {
+		// Headings kept with the block only move with it if they fit together.
+		if s.tail != nil && s.tail.lines+b.lines > s.opts.MaxHeight {
+			s.tail = nil
+		}
+		s.breakSlide(BreakOverflow)
+	}
+	s.add(b)
+}
+
+// flush places the pending blocks, breaking slides where optimalBreaks
+// chooses.
+func (s *splitter) flush() {
+	if len(s.pending) == 0 {
+		return
+	}
+	pending := s.pending
+	s.pending = nil
+
+	heights := make([]int, len(pending))
+	lead := make([]int, len(pending))
+	keep := make([]bool, len(pending))
+	// The breadcrumb above each block follows the headings pending before it.
+	headings := s.headings
+	for i, b := range pending {
+		heights[i] = b.lines
+		lead[i] = s.breadcrumbSpace(b)
+		keep[i] = s.keepWithNext && headingLevel(b.node) > 0
+		if level := headingLevel(b.node); level > 0 {
+			s.headings = pushHeading(s.headings, heading{level: level, text: headingText(b.node, s.source)})
+		}
+	}
+	s.headings = headings
+	keepInitial := s.keepWithNext && s.tail != nil && s.tail.offset > 0
+	starts := optimalBreaks(heights, lead, s.lines, s.opts.MaxHeight, keep, keepInitial)
+	for i, b := range pending {
+		if len(starts) > 0 && starts[0] == i {
+			s.breakSlide(BreakOverflow)
+			starts = starts[1:]
+		}
+		s.add(b)
+	}
+}
+
+// startOversized closes the current slide before an oversized block and
+// returns the number of lines of carried-over headings the block's first part
+// has to share its slide with.
+func (s *splitter) startOversized() int {
+	s.breakSlide(BreakOversized)
+	return s.lines
+}
+
+// limits returns the widow and orphan control for split blocks.
+func (s *splitter) limits() breakLimits {
+	return breakLimits{before: s.opts.MinLinesBeforeBreak, after: s.opts.MinLinesAfterBreak}
+}
+
+// note renders a continuation note under the headings above the slide. If
+// the template fails, the note is left out and the first such error is kept
+// in s.err for splitSource to return.
+func (s *splitter) note(data NoteData) string {
+	data.Headings = s.headingPath()
+	data.Heading = s.innermostHeading()
+	note, err := s.notes.render(data)
+	if err != nil {
+		if s.err == nil {
+			s.err = fmt.Errorf("executing %s note: %w", data.Kind, err)
+		}
+		return ""
+	}
+	return note
+}
+
+// noteRoom returns the lines to leave for the continuation note of kind
+// under each part of a block split into at most parts parts. The note is
+// measured for the last of them, whose numbers are the longest.
+func (s *splitter) noteRoom(kind string, parts int) int {
+	return noteHeight(s.note(NoteData{Kind: kind, Part: parts, Total: parts}), s.layout)
+}
+
+// breadcrumb returns the trail of headings repeated at the top of a slide
+// that continues a section, or "" when there is none.
+func (s *splitter) breadcrumb() string {
+	if !s.opts.HeadingBreadcrumb || len(s.headings) == 0 {
+		return ""
+	}
+	return s.note(NoteData{Kind: NoteBreadcrumb})
+}
+
+// breadcrumbSpace returns the lines the breadcrumb takes on a slide starting
+// with b. Slides starting with a heading get none, nor do slides the
+// breadcrumb would make overflow.
+func (s *splitter) breadcrumbSpace(b block) int {
+	if headingLevel(b.node) > 0 {
+		return 0
+	}
+	space := noteSpace(noteLines(s.breadcrumb()))
+	if !s.opts.SplitBySection && space+b.lines > s.opts.MaxHeight {
+		return 0
+	}
+	return space
+}
+
+// partHeight returns the height each part of a split block may take, leaving
+// room for the breadcrumb.
+func (s *splitter) partHeight() int {
+	return s.opts.MaxHeight - noteSpace(noteLines(s.breadcrumb()))
+}
+
+// emitParts writes each part of an oversized block n as a slide of its own,
+// the first one after anything left on the current slide. Each part gets the
+// continuation note for kind.
+func (s *splitter) emitParts(kind string, n ast.Node, parts []slidePart) {
+	crumb := s.breadcrumb()
+	for i, part := range parts {
+		lines := s.layout.lineCount(string(part.content), n)
+		if crumb != "" && (i > 0 || s.current.Len() == 0 && len(s.slides) > 0) {
+			part.content = append([]byte(crumb+"\n\n"), part.content...)
+			lines += noteSpace(noteLines(crumb))
+		}
+		if note := s.note(NoteData{Kind: kind, Part: i + 1, Total: len(parts)}); note != "" {
+			part.content = append(part.content, "\n"+note...)
+			lines += noteSpace(noteHeight(note, s.layout))
+		}
+		slide := Slide{
+			Content:  part.content,
+			Start:    part.start,
+			End:      part.stop,
+			Reason:   BreakContinuation,
+			Headings: s.headingPath(),
+			Anchor:   s.innermostAnchor(),
+			Kinds:    []string{kind},
+			Lines:    lines,
+		}
+		if i == 0 {
+			slide.Reason = s.reason
+			if s.current.Len() > 0 {
+				slide.Content = append(bytes.Clone(s.current.Bytes()), part.content...)
+				slide.Start, slide.End = widenRange(s.start, s.stop, part.start, part.stop)
+				slide.Headings = s.path
+				slide.Anchor = s.anchor
+				slide.Kinds = append(s.kinds, kind)
+				slide.Lines += s.lines
+				s.current.Reset()
+				s.lines = 0
+				s.start, s.stop = -1, -1
+				s.tail = nil
+				s.kinds = nil
+			}
+		}
+		s.appendSlide(slide)
+	}
+	if len(parts) > 0 {
+		s.reason = BreakOversized
+	}
+}
+
+// finish closes the last slide and returns all the slides.
+func (s *splitter) finish() []Slide {
+	s.keepWithNext = false
+	s.breakSlide(BreakOverflow)
+	return s.slides
+}
+
+// appendSlide numbers slide, gives it its ID and adds it to the slides. Its
+// kinds are reduced to one of each.
+func (s *splitter) appendSlide(slide Slide) {
+	slide.Index = len(s.slides) + 1
+	if len(slide.Headings) > 0 {
+		slide.Section = slide.Headings[len(slide.Headings)-1]
+	}
+	var kinds []string
+	for _, kind := range slide.Kinds {
+		if !slices.Contains(kinds, kind) {
+			kinds = append(kinds, kind)
+		}
+	}
+	slide.Kinds = kinds
+	base := cmp.Or(slide.Anchor, topAnchor)
+	n := 1
+	for _, other := range s.slides {
+		if cmp.Or(other.Anchor, topAnchor) == base {
+			n++
+		}
+	}
+	slide.ID = fmt.Sprintf("%s-%d", base, n)
+	s.slides = append(s.slides, slide)
+}
+
+// widenRange grows the range [start, stop) to cover [s, e). Ranges with a
+// negative start are unknown and ignored.
+func widenRange(start, stop, s, e int) (int, int) {
+	if s < 0 {
+		return start, stop
+	}
+	if start < 0 || s < start {
+		start = s
+	}
+	if stop < 0 || e > stop {
+		stop = e
+	}
+	return start, stop
+}
+
+// sourceRange returns the byte range of the whole source lines covered by n,
+// or -1, -1 if n has no position in the source.
+func sourceRange(n ast.Node, source []byte) (int, int) {
+	start, stop := getNodeBounds(n)
+	fenced, isFenced := n.(*ast.FencedCodeBlock)
+	if isFenced && fenced.Info != nil {
+		start, stop = widenRange(start, stop, fenced.Info.Segment.Start, fenced.Info.Segment.Stop)
+	}
+	if start == -1 {
+		return -1, -1
+	}
+	start, stop = expandToLines(source, start, stop)
+	if isFenced {
+		// The fences are not part of the node's segments.
+		if fenced.Info == nil && start > 0 {
+			start, _ = expandToLines(source, start-1, start-1)
+		}
+		if next := bytes.TrimLeft(source[stop:], " \t"); bytes.HasPrefix(next, []byte("```")) || bytes.HasPrefix(next, []byte("~~~")) {
+			_, stop = expandToLines(source, stop, stop)
+		}
+	}
+	return start, stop
+}
+
+// expandToLines widens [start, stop) to whole lines, including the trailing newline.
+func expandToLines(source []byte, start, stop int) (int, int) {
+	for start > 0 && source[start-1] != '\n' {
+		start--
+	}
+	for stop < len(source) && source[stop] != '\n' {
+		stop++
+	}
+	// Include the newline at the end if present
+	if stop < len(source) && source[stop] == '\n' {
+		stop++
+	}
+	return start, stop
+}
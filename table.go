@@ -0,0 +1,130 @@
+package mdsplit
+
+import (
+	"strings"
+)
+
+// tableGroup is a table, or a group of the columns of a wide table.
+type tableGroup struct {
+	table       block
+	first, last int // 1-based range of the columns in the group
+	columns     int // Number of columns in the table, or 0 for a whole table
+}
+
+// splitTableColumns splits a table wider than the slide into groups of
+// columns that each fit, repeating the 1-based key column at the start of
+// every group that would not otherwise hold it. A table that fits, or that
+// the slide has no width for, is returned whole.
+func splitTableColumns(b block, key int, lay layout) []tableGroup {
+	lines := trimmedLines(b.content)
+	if lay.width <= 0 || len(lines) < 2 {
+		return []tableGroup{{table: b}}
+	}
+	rows := make([][]string, len(lines))
+	for i, line := range lines {
+		rows[i] = tableCells(line)
+	}
+	count := len(rows[0])
+	for i, row := range rows {
+		// Rows are padded or cut to the header's width, as GFM renders them.
+		rows[i] = append(row, make([]string, max(0, count-len(row)))...)[:count]
+	}
+
+	// Every cell gets an em of padding.
+	widths := make([]float64, count)
+	for i, row := range rows {
+		if i == 1 {
+			continue // The delimiter row
+		}
+		for col, cell := range row {
+			widths[col] = max(widths[col], lay.measurer.TextWidth(displayText(cell), lay.fontSize, false)+lay.fontSize)
+		}
+	}
+	key-- // To a column index
+	if key < 0 || key >= count {
+		key = -1
+	}
+	groups := columnGroups(widths, lay.width, key)
+	if len(groups) <= 1 {
+		return []tableGroup{{table: b}}
+	}
+
+	var parts []tableGroup
+	for _, group := range groups {
+		var table strings.Builder
+		for _, row := range rows {
+			cells := make([]string, len(group))
+			for i, col := range group {
+				cells[i] = row[col]
+			}
+			table.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+		}
+		table.WriteString("\n")
+
+		first := group[0]
+		if first == key && len(group) > 1 && group[1] != key+1 {
+			first = group[1]
+		}
+		content := table.String()
+		parts = append(parts, tableGroup{
+			table:   block{node: b.node, content: []byte(content), lines: strings.Count(content, "\n"), start: b.start, stop: b.stop},
+			first:   first + 1,
+			last:    group[len(group)-1] + 1,
+			columns: count,
+		})
+	}
+	return parts
+}
+
+// columnGroups packs columns of the given widths into groups no wider than
+// width, each holding at least one column besides the key column, which
+// leads every group. A key of -1 means there is none.
+func columnGroups(widths []float64, width float64, key int) [][]int {
+	var groups [][]int
+	var group []int
+	used, added := 0.0, 0
+	for col, w := range widths {
+		if col == key {
+			continue
+		}
+		if added > 0 && used+w > width {
+			groups = append(groups, group)
+			added = 0
+		}
+		if added == 0 {
+			group, used = nil, 0
+			if key >= 0 {
+				group, used = []int{key}, widths[key]
+			}
+		}
+		group = append(group, col)
+		used += w
+		added++
+	}
+	if added > 0 {
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+// tableCells splits a table line into its trimmed cells at the pipes that
+// are not escaped.
+func tableCells(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, "\\|") {
+		line = line[:len(line)-1]
+	}
+	var cells []string
+	start := 0
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '|':
+			cells = append(cells, strings.TrimSpace(line[start:i]))
+			start = i + 1
+		}
+	}
+	return append(cells, strings.TrimSpace(line[start:]))
+}
@@ -0,0 +1,43 @@
+package mdsplit
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTableCells(t *testing.T) {
+	testCases := []struct {
+		line     string
+		expected []string
+	}{
+		{line: "| a | b |", expected: []string{"a", "b"}},
+		{line: "a | b", expected: []string{"a", "b"}},
+		{line: "|:---|---:|", expected: []string{":---", "---:"}},
+		{line: `| a \| b | c \|`, expected: []string{`a \| b`, `c \|`}},
+	}
+	for _, tc := range testCases {
+		if got := tableCells(tc.line); !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("tableCells(%q) = %q, expected %q", tc.line, got, tc.expected)
+		}
+	}
+}
+
+func TestColumnGroups(t *testing.T) {
+	widths := []float64{20, 40, 40, 40, 40}
+	testCases := []struct {
+		name     string
+		key      int
+		expected [][]int
+	}{
+		{name: "no key", key: -1, expected: [][]int{{0, 1, 2}, {3, 4}}},
+		{name: "first column key", key: 0, expected: [][]int{{0, 1, 2}, {0, 3, 4}}},
+		{name: "key too wide for more", key: 1, expected: [][]int{{1, 0, 2}, {1, 3}, {1, 4}}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := columnGroups(widths, 100, tc.key); !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("columnGroups = %v, expected %v", got, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,261 @@
+package mdsplit
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	gfm "github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/text"
+)
+
+// DefaultWatchInterval is how often Watch polls for changes by default.
+const DefaultWatchInterval = 500 * time.Millisecond
+
+// WatchOptions configures Watch.
+type WatchOptions struct {
+	Interval time.Duration     // How often the files are checked for changes (default: DefaultWatchInterval)
+	Report   func(SplitChange) // Called after every split (default: none)
+}
+
+// SplitChange compares the slides of a split made by Watch with those of the
+// split before it.
+type SplitChange struct {
+	Before, After int      // Number of slides before and after
+	Added         []string // Slide files written that did not exist before
+	Changed       []string // Slide files rewritten with new content
+	Removed       []string // Slide files of the split before that are gone
+	Err           error    // Why the split failed, leaving the slides as they were
+}
+
+// String describes the change compactly, such as "12 → 13 slides: 1 added,
+// 2 changed".
+func (c SplitChange) String() string {
+	if c.Err != nil {
+		return "error: " + c.Err.Error()
+	}
+	var b strings.Builder
+	if c.Before != c.After {
+		fmt.Fprintf(&b, "%d → ", c.Before)
+	}
+	fmt.Fprintf(&b, "%d slide", c.After)
+	if c.After != 1 {
+		b.WriteByte('s')
+	}
+	var parts []string
+	for _, part := range []struct {
+		n    int
+		what string
+	}{{len(c.Added), "added"}, {len(c.Changed), "changed"}, {len(c.Removed), "removed"}} {
+		if part.n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", part.n, part.what))
+		}
+	}
+	if len(parts) == 0 {
+		return b.String() + ", unchanged"
+	}
+	return b.String() + ": " + strings.Join(parts, ", ")
+}
+
+// Watch splits the Markdown file called name into opts.OutDir, as Split
+// does, and again whenever it or a local file it links to, such as an image,
+// changes, until ctx is done. The files are polled rather than watched
+// through the operating system, so any file system will do. Only slides
+// whose content changed are rewritten, and slides the file no longer
+// produces are removed. A split that fails is reported and the slides are
+// left as they were.
+func Watch(ctx context.Context, name string, opts SplitOptions, wopts WatchOptions) error {
+	dir := cmp.Or(opts.OutDir, ".")
+	w, err := NewDirWriter(dir)
+	if err != nil {
+		return err
+	}
+	if opts.SourceName == "" {
+		opts.SourceName = name
+	}
+	wt := &watcher{name: name, dir: dir, writer: w, opts: opts}
+
+	interval := cmp.Or(wopts.Interval, DefaultWatchInterval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for ctx.Err() == nil {
+		change := wt.split()
+		if wopts.Report != nil {
+			wopts.Report(change)
+		}
+		// Wait for a watched file to change.
+		for slices.Equal(wt.stamps, wt.stamp()) {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+			}
+		}
+	}
+	return nil
+}
+
+// watcher holds what Watch knows of the slides it last wrote.
+type watcher struct {
+	name   string
+	dir    string
+	writer *DirWriter
+	opts   SplitOptions
+	slides int               // Number of slides last written
+	files  map[string][]byte // Content of the files last written, or nil before the first split
+	watch  []string          // Files whose changes cause a split
+	stamps []fileStamp       // State of the watched files when last split
+}
+
+// fileStamp is what polling looks at to tell whether a file changed.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+	exists  bool
+}
+
+// stamp returns the state of the watched files.
+func (wt *watcher) stamp() []fileStamp {
+	stamps := make([]fileStamp, len(wt.watch))
+	for i, name := range wt.watch {
+		stamps[i] = stampFile(name)
+	}
+	return stamps
+}
+
+// stampFile returns the state of the file called name.
+func stampFile(name string) fileStamp {
+	info, err := os.Stat(name)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{modTime: info.ModTime(), size: info.Size(), exists: true}
+}
+
+// split splits the file again and writes the files that changed.
+func (wt *watcher) split() SplitChange {
+	change := SplitChange{Before: wt.slides, After: wt.slides}
+
+	// The file is stamped before it is read, so that a change made while it
+	// is being read is picked up by the next poll.
+	stamp := stampFile(wt.name)
+	data, err := os.ReadFile(wt.name)
+	if err != nil {
+		wt.watch, wt.stamps = []string{wt.name}, []fileStamp{stamp}
+		change.Err = err
+		return change
+	}
+	wt.watch = append([]string{wt.name}, linkedFiles(data, filepath.Dir(wt.name))...)
+	wt.stamps = append([]fileStamp{stamp}, wt.stamp()[1:]...)
+
+	slides, err := SplitSlides(data, wt.opts)
+	if err != nil {
+		change.Err = err
+		return change
+	}
+	files := map[string][]byte{}
+	var names []string
+	for _, slide := range slides {
+		files[slide.Name] = slide.Content
+		names = append(names, slide.Name)
+	}
+	manifest := NewManifest(slides, data, wt.opts.SourceName)
+	if wt.opts.Manifest {
+		if files[ManifestName], err = manifest.JSON(); err != nil {
+			change.Err = err
+			return change
+		}
+		names = append(names, ManifestName)
+	}
+	if wt.opts.Index {
+		files[IndexName] = manifest.Index()
+		names = append(names, IndexName)
+	}
+
+	for i, name := range names {
+		old, ok := wt.files[name]
+		if wt.files == nil {
+			// Slides left by an earlier run are kept if they are up to date.
+			old, err = os.ReadFile(filepath.Join(wt.dir, filepath.FromSlash(name)))
+			ok = err == nil
+		}
+		if ok && bytes.Equal(old, files[name]) {
+			continue
+		}
+		if err := wt.writer.WriteFile(name, files[name]); err != nil {
+			change.Err = err
+			return change
+		}
+		switch {
+		case i >= len(slides):
+		case ok:
+			change.Changed = append(change.Changed, name)
+		default:
+			change.Added = append(change.Added, name)
+		}
+	}
+	for name := range wt.files {
+		if _, ok := files[name]; ok {
+			continue
+		}
+		if err := os.Remove(filepath.Join(wt.dir, filepath.FromSlash(name))); err != nil && !os.IsNotExist(err) {
+			change.Err = err
+			return change
+		}
+		if name != ManifestName && name != IndexName {
+			change.Removed = append(change.Removed, name)
+		}
+	}
+	slices.Sort(change.Removed)
+
+	wt.files = files
+	wt.slides = len(slides)
+	change.After = len(slides)
+	return change
+}
+
+// linkedFiles returns the local files that the links and images in data, a
+// Markdown file in dir, point to and that exist.
+func linkedFiles(data []byte, dir string) []string {
+	if fm, ok := findFrontMatter(data); ok {
+		data = fm.blank(data)
+	}
+	root := goldmark.New(goldmark.WithExtensions(gfm.GFM)).Parser().Parse(text.NewReader(data))
+	var files []string
+	_ = ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		var dest []byte
+		switch n := n.(type) {
+		case *ast.Link:
+			dest = n.Destination
+		case *ast.Image:
+			dest = n.Destination
+		default:
+			return ast.WalkContinue, nil
+		}
+		p, ok := relativeLink(string(dest))
+		if !ok {
+			return ast.WalkContinue, nil
+		}
+		if unescaped, err := url.PathUnescape(p); err == nil {
+			p = unescaped
+		}
+		name := filepath.Join(dir, filepath.FromSlash(p))
+		if info, err := os.Stat(name); err == nil && !info.IsDir() && !slices.Contains(files, name) {
+			files = append(files, name)
+		}
+		return ast.WalkContinue, nil
+	})
+	return files
+}
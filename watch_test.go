@@ -0,0 +1,115 @@
+package mdsplit
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSplitChangeString(t *testing.T) {
+	testCases := []struct {
+		change   SplitChange
+		expected string
+	}{
+		{SplitChange{Before: 0, After: 2, Added: []string{"a", "b"}}, "0 → 2 slides: 2 added"},
+		{SplitChange{Before: 3, After: 3, Changed: []string{"a"}}, "3 slides: 1 changed"},
+		{SplitChange{Before: 3, After: 1, Changed: []string{"a"}, Removed: []string{"b", "c"}}, "3 → 1 slide: 1 changed, 2 removed"},
+		{SplitChange{Before: 2, After: 2}, "2 slides, unchanged"},
+		{SplitChange{Before: 2, After: 2, Err: os.ErrNotExist}, "error: file does not exist"},
+	}
+	for _, tc := range testCases {
+		if got := tc.change.String(); got != tc.expected {
+			t.Errorf("String() = %q, expected %q", got, tc.expected)
+		}
+	}
+}
+
+func TestLinkedFiles(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"img/a.png":   "png",
+		"other.md":    "# Other",
+		"my file.png": "png",
+	})
+	data := []byte("![a](img/a.png) [other](other.md#x) [gone](gone.md) [web](https://example.com) ![b](my%20file.png) [again](other.md)\n")
+	expected := []string{
+		filepath.Join(dir, "img", "a.png"),
+		filepath.Join(dir, "other.md"),
+		filepath.Join(dir, "my file.png"),
+	}
+	if got := linkedFiles(data, dir); !reflect.DeepEqual(got, expected) {
+		t.Errorf("linkedFiles = %q, expected %q", got, expected)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "deck.md")
+	out := filepath.Join(dir, "slides")
+	// Files are replaced whole so that no poll sees one half written.
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(in+".tmp", []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(in+".tmp", in); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("# One\n\nFirst.\n\n# Two\n\nSecond.\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	changes := make(chan SplitChange)
+	done := make(chan error)
+	go func() {
+		done <- Watch(ctx, in, SplitOptions{OutDir: out, HeadingBreakLevel: 1}, WatchOptions{
+			Interval: 10 * time.Millisecond,
+			Report:   func(c SplitChange) { changes <- c },
+		})
+	}()
+	next := func() SplitChange {
+		t.Helper()
+		select {
+		case c := <-changes:
+			return c
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for a split")
+			return SplitChange{}
+		}
+	}
+
+	if c := next(); c.String() != "0 → 2 slides: 2 added" {
+		t.Errorf("first split: %s", c)
+	}
+	before, err := os.Stat(filepath.Join(out, "slide-1.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	write("# One\n\nFirst.\n\n# Two\n\nSecond, changed.\n\n# Three\n\nThird.\n")
+	if c := next(); !reflect.DeepEqual(c.Changed, []string{"slide-2.md"}) || !reflect.DeepEqual(c.Added, []string{"slide-3.md"}) {
+		t.Errorf("second split: %s", c)
+	}
+	after, err := os.Stat(filepath.Join(out, "slide-1.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !after.ModTime().Equal(before.ModTime()) {
+		t.Errorf("unchanged slide-1.md was rewritten")
+	}
+
+	write("# One\n\nFirst.\n")
+	if c := next(); !reflect.DeepEqual(c.Removed, []string{"slide-2.md", "slide-3.md"}) {
+		t.Errorf("third split: %s", c)
+	}
+	if _, err := os.Stat(filepath.Join(out, "slide-3.md")); !os.IsNotExist(err) {
+		t.Errorf("slide-3.md was not removed: %v", err)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Watch returned %v", err)
+	}
+}
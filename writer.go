@@ -0,0 +1,312 @@
+package mdsplit
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SlideWriter receives the files produced by Split.
+type SlideWriter interface {
+	// WriteFile writes data to the file called name, a slash-separated path
+	// relative to the root of the output.
+	WriteFile(name string, data []byte) error
+	// Close flushes anything still buffered by the writer.
+	Close() error
+}
+
+// DefaultStreamSeparator separates slides written by a StreamWriter. It is a
+// thematic break, which Marp and reveal.js treat as a slide boundary.
+const DefaultStreamSeparator = "\n---\n\n"
+
+// DirWriter writes each file into a directory on disk. Files that already
+// hold the same content are not rewritten.
+type DirWriter struct {
+	Dir string
+
+	// Set up by NewCleaningDirWriter.
+	clean   CleanMode
+	dryRun  bool
+	temp    string          // Directory written to in place of Dir, if any
+	owned   []string        // Files of an earlier run that may be removed
+	written map[string]bool // Files written by this run
+	removed []string
+}
+
+// NewDirWriter creates dir if needed and returns a writer for it.
+func NewDirWriter(dir string) (*DirWriter, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &DirWriter{Dir: dir}, nil
+}
+
+func (w *DirWriter) WriteFile(name string, data []byte) error {
+	if w.written != nil {
+		w.written[path.Clean(name)] = true
+	}
+	if w.dryRun {
+		return nil
+	}
+	root := w.Dir
+	if w.temp != "" {
+		root = w.temp
+	}
+	p := filepath.Join(root, filepath.FromSlash(name))
+	// A file already holding data is left alone, keeping its modification
+	// time, so that whatever renders the slides can skip it. When swapping,
+	// the old file is linked into the new directory.
+	old := filepath.Join(w.Dir, filepath.FromSlash(name))
+	if w.temp == "" && sameContent(old, data) {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	if w.temp != "" && sameContent(old, data) && os.Link(old, p) == nil {
+		return nil
+	}
+	return os.WriteFile(p, data, 0644)
+}
+
+// sameContent reports whether the file called name holds data.
+func sameContent(name string, data []byte) bool {
+	info, err := os.Stat(name)
+	if err != nil || !info.Mode().IsRegular() || info.Size() != int64(len(data)) {
+		return false
+	}
+	content, err := os.ReadFile(name)
+	return err == nil && bytes.Equal(content, data)
+}
+
+// Close removes the stale files of an earlier run, if the writer was made to
+// clean up.
+func (w *DirWriter) Close() error {
+	switch w.clean {
+	case CleanSwap:
+		return w.swap()
+	case CleanManifest:
+		return w.prune()
+	}
+	return nil
+}
+
+// ZipWriter writes each file as an entry of a zip archive.
+type ZipWriter struct {
+	zw *zip.Writer
+}
+
+// NewZipWriter returns a writer producing a zip archive on w. Closing the
+// ZipWriter finishes the archive but does not close w.
+func NewZipWriter(w io.Writer) *ZipWriter {
+	return &ZipWriter{zw: zip.NewWriter(w)}
+}
+
+func (w *ZipWriter) WriteFile(name string, data []byte) error {
+	f, err := w.zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	return err
+}
+
+func (w *ZipWriter) Close() error {
+	return w.zw.Close()
+}
+
+// TarWriter writes each file as an entry of a tar archive.
+type TarWriter struct {
+	tw *tar.Writer
+}
+
+// NewTarWriter returns a writer producing a tar archive on w. Closing the
+// TarWriter finishes the archive but does not close w.
+func NewTarWriter(w io.Writer) *TarWriter {
+	return &TarWriter{tw: tar.NewWriter(w)}
+}
+
+func (w *TarWriter) WriteFile(name string, data []byte) error {
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	}
+	if err := w.tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := w.tw.Write(data)
+	return err
+}
+
+func (w *TarWriter) Close() error {
+	return w.tw.Close()
+}
+
+// StreamWriter writes every file to a single stream, one after another,
+// with Separator between them. File names are not recorded.
+type StreamWriter struct {
+	w         io.Writer
+	Separator string
+	written   bool
+}
+
+// NewStreamWriter returns a writer concatenating files onto w, separated by
+// DefaultStreamSeparator.
+func NewStreamWriter(w io.Writer) *StreamWriter {
+	return &StreamWriter{w: w, Separator: DefaultStreamSeparator}
+}
+
+func (w *StreamWriter) WriteFile(name string, data []byte) error {
+	if w.written {
+		if _, err := io.WriteString(w.w, w.Separator); err != nil {
+			return err
+		}
+	}
+	w.written = true
+	_, err := w.w.Write(data)
+	return err
+}
+
+func (w *StreamWriter) Close() error {
+	return nil
+}
+
+// MemWriter keeps every file in memory and exposes them as an fs.FS.
+type MemWriter struct {
+	mu    sync.Mutex
+	files map[string][]byte
+}
+
+// NewMemWriter returns an empty in-memory writer.
+func NewMemWriter() *MemWriter {
+	return &MemWriter{files: map[string][]byte{}}
+}
+
+func (w *MemWriter) WriteFile(name string, data []byte) error {
+	if !fs.ValidPath(name) {
+		return &fs.PathError{Op: "write", Path: name, Err: fs.ErrInvalid}
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.files[path.Clean(name)] = append([]byte(nil), data...)
+	return nil
+}
+
+func (w *MemWriter) Close() error {
+	return nil
+}
+
+// FS returns a snapshot of the files written so far.
+func (w *MemWriter) FS() fs.FS {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return memFS(maps.Clone(w.files))
+}
+
+// memFS is a read-only file system of files held in memory, keyed by their
+// slash-separated paths. Directories are implied by the paths of the files
+// in them.
+type memFS map[string][]byte
+
+func (m memFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if data, ok := m[name]; ok {
+		return &memFile{memInfo: memInfo{name: path.Base(name), size: int64(len(data))}, Reader: bytes.NewReader(data)}, nil
+	}
+	prefix := name + "/"
+	if name == "." {
+		prefix = ""
+	}
+	children := map[string]memInfo{}
+	for file, data := range m {
+		rest, ok := strings.CutPrefix(file, prefix)
+		if !ok {
+			continue
+		}
+		if child, _, nested := strings.Cut(rest, "/"); nested {
+			children[child] = memInfo{name: child, dir: true}
+		} else {
+			children[child] = memInfo{name: child, size: int64(len(data))}
+		}
+	}
+	if len(children) == 0 && name != "." {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	dir := &memDir{memInfo: memInfo{name: path.Base(name), dir: true}}
+	for _, child := range children {
+		dir.entries = append(dir.entries, fs.FileInfoToDirEntry(child))
+	}
+	slices.SortFunc(dir.entries, func(a, b fs.DirEntry) int { return strings.Compare(a.Name(), b.Name()) })
+	return dir, nil
+}
+
+// memInfo describes a file or directory of a memFS.
+type memInfo struct {
+	name string
+	size int64
+	dir  bool
+}
+
+func (i memInfo) Name() string       { return i.name }
+func (i memInfo) Size() int64        { return i.size }
+func (i memInfo) ModTime() time.Time { return time.Time{} }
+func (i memInfo) IsDir() bool        { return i.dir }
+func (i memInfo) Sys() any           { return nil }
+
+func (i memInfo) Mode() fs.FileMode {
+	if i.dir {
+		return fs.ModeDir | 0755
+	}
+	return 0644
+}
+
+// memFile is an open file of a memFS.
+type memFile struct {
+	memInfo
+	*bytes.Reader
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) { return f.memInfo, nil }
+func (f *memFile) Close() error               { return nil }
+
+// memDir is an open directory of a memFS.
+type memDir struct {
+	memInfo
+	entries []fs.DirEntry // Entries not yet read
+}
+
+func (d *memDir) Stat() (fs.FileInfo, error) { return d.memInfo, nil }
+func (d *memDir) Close() error               { return nil }
+
+func (d *memDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: fs.ErrInvalid}
+}
+
+func (d *memDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if n <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(d.entries))
+	entries := d.entries[:n]
+	d.entries = d.entries[n:]
+	return entries, nil
+}
@@ -0,0 +1,142 @@
+package mdsplit
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"cmp"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+const writerTestInput = "# Page 1\n\nSome content.\n\n# Page 2\n\nMore content.\n"
+
+func TestSplitWriters(t *testing.T) {
+	t.Run("zip", func(t *testing.T) {
+		var buf bytes.Buffer
+		w := NewZipWriter(&buf)
+		if err := Split([]byte(writerTestInput), SplitOptions{MaxHeight: 5, Writer: w}); err != nil {
+			t.Fatalf("Split failed: %v", err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+		zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		if err != nil {
+			t.Fatalf("Failed to read zip: %v", err)
+		}
+		var names []string
+		for _, f := range zr.File {
+			names = append(names, f.Name)
+		}
+		if len(names) != 2 || names[0] != "slide-1.md" || names[1] != "slide-2.md" {
+			t.Errorf("Unexpected zip entries: %v", names)
+		}
+	})
+
+	t.Run("tar", func(t *testing.T) {
+		var buf bytes.Buffer
+		w := NewTarWriter(&buf)
+		if err := Split([]byte(writerTestInput), SplitOptions{MaxHeight: 5, Writer: w}); err != nil {
+			t.Fatalf("Split failed: %v", err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+		tr := tar.NewReader(&buf)
+		hdr, err := tr.Next()
+		if err != nil {
+			t.Fatalf("Failed to read tar: %v", err)
+		}
+		content, _ := io.ReadAll(tr)
+		if hdr.Name != "slide-1.md" || string(content) != "# Page 1\n\nSome content.\n\n" {
+			t.Errorf("Unexpected first tar entry %s: %q", hdr.Name, content)
+		}
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := Split([]byte(writerTestInput), SplitOptions{MaxHeight: 5, Writer: NewStreamWriter(&buf)}); err != nil {
+			t.Fatalf("Split failed: %v", err)
+		}
+		expected := "# Page 1\n\nSome content.\n\n" + DefaultStreamSeparator + "# Page 2\n\nMore content.\n\n"
+		if buf.String() != expected {
+			t.Errorf("Unexpected stream output:\n%q\nExpected:\n%q", buf.String(), expected)
+		}
+	})
+
+	t.Run("memory", func(t *testing.T) {
+		w := NewMemWriter()
+		if err := Split([]byte(writerTestInput), SplitOptions{MaxHeight: 5, Writer: w}); err != nil {
+			t.Fatalf("Split failed: %v", err)
+		}
+		content, err := fs.ReadFile(w.FS(), "slide-2.md")
+		if err != nil {
+			t.Fatalf("Failed to read slide-2.md: %v", err)
+		}
+		if string(content) != "# Page 2\n\nMore content.\n\n" {
+			t.Errorf("Unexpected slide-2.md content: %q", content)
+		}
+	})
+}
+
+func TestDirWriterKeepsUnchangedFiles(t *testing.T) {
+	past := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, mode := range []CleanMode{CleanNone, CleanSwap} {
+		t.Run(cmp.Or(string(mode), "none"), func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "out")
+			if err := Split([]byte(writerTestInput), SplitOptions{OutDir: dir, MaxHeight: 5}); err != nil {
+				t.Fatalf("first Split failed: %v", err)
+			}
+			for _, name := range []string{"slide-1.md", "slide-2.md"} {
+				if err := os.Chtimes(filepath.Join(dir, name), past, past); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			w, err := NewCleaningDirWriter(dir, mode, false)
+			if err != nil {
+				t.Fatalf("NewCleaningDirWriter failed: %v", err)
+			}
+			changed := strings.Replace(writerTestInput, "More", "Other", 1)
+			if err := Split([]byte(changed), SplitOptions{Writer: w, MaxHeight: 5}); err != nil {
+				t.Fatalf("second Split failed: %v", err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close failed: %v", err)
+			}
+
+			for name, kept := range map[string]bool{"slide-1.md": true, "slide-2.md": false} {
+				info, err := os.Stat(filepath.Join(dir, name))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if info.ModTime().Equal(past) != kept {
+					t.Errorf("%s modified at %v, expected it kept: %v", name, info.ModTime(), kept)
+				}
+			}
+			content, err := os.ReadFile(filepath.Join(dir, "slide-2.md"))
+			if err != nil || string(content) != "# Page 2\n\nOther content.\n\n" {
+				t.Errorf("slide-2.md holds %q, %v", content, err)
+			}
+		})
+	}
+}
+
+func TestMemWriterFS(t *testing.T) {
+	w := NewMemWriter()
+	for _, name := range []string{"index.md", "deck/slide-1.md", "deck/notes/slide-1.md"} {
+		if err := w.WriteFile(name, []byte("# "+name+"\n")); err != nil {
+			t.Fatalf("WriteFile(%q) failed: %v", name, err)
+		}
+	}
+	if err := fstest.TestFS(w.FS(), "index.md", "deck/slide-1.md", "deck/notes/slide-1.md"); err != nil {
+		t.Error(err)
+	}
+}